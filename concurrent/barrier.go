@@ -0,0 +1,85 @@
+package concurrent
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrBarrierBroken determines a goroutine left the barrier while others were waiting, so the barrier is broken.
+var ErrBarrierBroken = errors.New("concurrent: barrier broken")
+
+// Barrier is a cyclic synchronization aid that lets a number of goroutines, called parties, wait for each other.
+// Each party blocks in Await until all parties arrived, then all of them are released and the barrier is reset
+// for the next round, so it can be reused by phased parallel algorithms.
+//
+// If the context of a waiting party is done, the party leaves the barrier and the round is broken:
+// the other parties waiting in the round return ErrBarrierBroken, and the barrier is reset for the next round.
+type Barrier struct {
+	mutex   *sync.Mutex
+	parties int
+	arrived int
+	round   *round
+}
+
+// round is a round of a barrier, whose done channel is closed when the round is completed or broken.
+type round struct {
+	done   chan struct{}
+	broken bool
+}
+
+// NewBarrier creates a new barrier for the number of parties provided, which should be at least 1.
+func NewBarrier(parties int) (*Barrier, error) {
+	if parties < 1 {
+		return nil, errors.New("concurrent: invalid argument")
+	}
+
+	return &Barrier{mutex: &sync.Mutex{}, parties: parties, round: &round{done: make(chan struct{})}}, nil
+}
+
+// Await blocks until all parties arrived, or the context is done.
+// It returns nil when the round is completed, ErrBarrierBroken if another party left the round,
+// or the error of the context if the context is done before the round is completed.
+func (b *Barrier) Await(ctx context.Context) error {
+	b.mutex.Lock()
+	r := b.round
+	b.arrived++
+	if b.arrived == b.parties {
+		b.reset()
+		b.mutex.Unlock()
+		return nil
+	}
+	b.mutex.Unlock()
+
+	select {
+	case <-r.done:
+		if r.broken {
+			return ErrBarrierBroken
+		}
+
+		return nil
+	case <-ctx.Done():
+		b.mutex.Lock()
+		defer b.mutex.Unlock()
+
+		if b.round != r {
+			// The round is finished right before the context is done.
+			if r.broken {
+				return ErrBarrierBroken
+			}
+
+			return nil
+		}
+
+		r.broken = true
+		b.reset()
+		return ctx.Err()
+	}
+}
+
+// reset releases the parties of the current round and starts a new round.
+func (b *Barrier) reset() {
+	close(b.round.done)
+	b.round = &round{done: make(chan struct{})}
+	b.arrived = 0
+}
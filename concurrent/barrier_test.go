@@ -0,0 +1,87 @@
+package concurrent
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBarrierRounds(t *testing.T) {
+	const parties, rounds = 4, 3
+	b, e := NewBarrier(parties)
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	mutex := &sync.Mutex{}
+	arrived := make([]int, rounds)
+
+	wg := &sync.WaitGroup{}
+	wg.Add(parties)
+	for i := 0; i < parties; i++ {
+		go func() {
+			defer wg.Done()
+			for round := 0; round < rounds; round++ {
+				mutex.Lock()
+				arrived[round]++
+				mutex.Unlock()
+
+				if e := b.Await(context.Background()); e != nil {
+					t.Error(e)
+					return
+				}
+
+				mutex.Lock()
+				if arrived[round] != parties {
+					t.Errorf("released from round %d with %d arrived", round, arrived[round])
+				}
+				mutex.Unlock()
+			}
+		}()
+	}
+
+	wg.Wait()
+}
+
+func TestBarrierBroken(t *testing.T) {
+	b, _ := NewBarrier(3)
+
+	waiting := make(chan error, 1)
+	go func() { waiting <- b.Await(context.Background()) }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if e := b.Await(ctx); e != context.DeadlineExceeded {
+		t.Errorf("Await = %v, want context.DeadlineExceeded", e)
+	}
+
+	select {
+	case e := <-waiting:
+		if e != ErrBarrierBroken {
+			t.Errorf("waiting party = %v, want ErrBarrierBroken", e)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("waiting party is not released")
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- b.Await(context.Background()) }()
+	go func() { done <- b.Await(context.Background()) }()
+	if e := b.Await(context.Background()); e != nil {
+		t.Errorf("Await after reset = %v, want nil", e)
+	}
+
+	for i := 0; i < 2; i++ {
+		if e := <-done; e != nil {
+			t.Errorf("Await after reset = %v, want nil", e)
+		}
+	}
+}
+
+func TestNewBarrierInvalidArgument(t *testing.T) {
+	if _, e := NewBarrier(0); e == nil {
+		t.Error("NewBarrier(0) succeeded, want an error")
+	}
+}
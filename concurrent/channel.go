@@ -0,0 +1,49 @@
+package concurrent
+
+import "time"
+
+// DebounceChan returns a channel that receives only the latest value sent on the in channel,
+// after a quiet window of d is passed without receiving any other value. Intermediate values are dropped.
+// When the in channel is closed, the pending value if any is sent and the returned channel is closed.
+func DebounceChan(in <-chan interface{}, d time.Duration) <-chan interface{} {
+	out := make(chan interface{})
+
+	go func() {
+		defer close(out)
+
+		var latest interface{}
+		pending := false
+		timer := time.NewTimer(d)
+		timer.Stop()
+
+		for {
+			select {
+			case v, ok := <-in:
+				if !ok {
+					if pending {
+						out <- latest
+					}
+
+					return
+				}
+
+				latest, pending = v, true
+				if !timer.Stop() {
+					select {
+					case <-timer.C:
+					default:
+					}
+				}
+
+				timer.Reset(d)
+			case <-timer.C:
+				if pending {
+					out <- latest
+					latest, pending = nil, false
+				}
+			}
+		}
+	}()
+
+	return out
+}
@@ -0,0 +1,35 @@
+package concurrent
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDebounceChan(t *testing.T) {
+	in := make(chan interface{})
+	out := DebounceChan(in, 20*time.Millisecond)
+
+	for i := 1; i <= 3; i++ {
+		in <- i
+	}
+
+	select {
+	case v := <-out:
+		if v != 3 {
+			t.Errorf("debounced value = %v, want 3", v)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no debounced value")
+	}
+
+	in <- 4
+	close(in)
+
+	if v, ok := <-out; !ok || v != 4 {
+		t.Errorf("pending value on close = %v, %v, want 4", v, ok)
+	}
+
+	if _, ok := <-out; ok {
+		t.Error("out is not closed after in is closed")
+	}
+}
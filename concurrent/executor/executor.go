@@ -4,101 +4,596 @@ package executor
 
 import (
 	"container/ring"
+	"context"
 	"errors"
 	"runtime"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/lireza/lib/concurrent"
 )
 
+// ErrExecutorShutdown determines the executor is shutdown, so can not execute runners anymore.
+var ErrExecutorShutdown = errors.New("executor: executor is shutdown")
+
 // Executor is a thread pool abstraction.
 type Executor interface {
 	// Execute executes the runner instance passed to method.
 	// Whether or not the runner will be called on new thread depends on implementation.
-	Execute(runner concurrent.Runner)
+	// It returns ErrExecutorShutdown if the executor is already shutdown.
+	Execute(runner concurrent.Runner) error
 
 	// Shutdown shutdowns the executor.
 	Shutdown()
 
 	// AwaitTermination waits until the executor is shutdown, so blocks the calling goroutine.
+	// It returns immediately if the executor is already terminated, and can be called multiple times.
 	AwaitTermination()
 }
 
 // RoundRobinExecutor is an executor implementation that contains some threads,
 // and passes runners to threads in a round robin fashion.
 type RoundRobinExecutor struct {
-	mutex    *sync.Mutex
-	ids      *ring.Ring
-	channels map[int]chan concurrent.Runner
-	shutdown map[int]chan struct{}
-	wg       *sync.WaitGroup
+	mutex     *sync.Mutex
+	ids       *ring.Ring
+	channels  map[int]chan concurrent.Runner
+	shutdown  map[int]chan struct{}
+	completed map[int]*uint64
+	queueSize int
+	wg        *sync.WaitGroup
+	sending   *sync.WaitGroup
+	down      bool
+	options   *options
+	counters  *counters
+	least     bool
+	slots     chan struct{}
+}
+
+// Stats contains the statistics of an executor.
+type Stats struct {
+	// Submitted is the number of runners queued since the executor is created,
+	// excluding the runners returned by ShutdownNow.
+	Submitted uint64
+
+	// Completed is the number of runners executed since the executor is created.
+	Completed uint64
+
+	// Queued is the number of runners waiting in queues to be executed.
+	Queued uint64
+
+	// Active is the number of threads executing a runner.
+	Active uint64
+}
+
+// WorkerStat contains the statistics of a thread of an executor.
+type WorkerStat struct {
+	// ID is the id of thread, starting from 1.
+	ID int
+
+	// Completed is the number of runners executed by the thread since it is started.
+	Completed uint64
+
+	// Queued is the number of runners waiting in the queue of thread to be executed.
+	Queued int
+}
+
+// counters contains the counters used to provide the statistics of an executor, updated atomically.
+type counters struct {
+	submitted uint64
+	completed uint64
+	active    uint64
 }
 
 // NewRoundRobinExecutor creates a new executor based on round robin distribution concept.
 // The number of threads in executor is defined through nThreads.
 // Each thread will have a queue for runners and the size of queues is defined through threadQueueSize.
+// The executor can be configured using the options provided, for example to handle panics of runners.
 // In case of errors during executor creation the error will be return.
-func NewRoundRobinExecutor(nThreads, threadQueueSize int) (*RoundRobinExecutor, error) {
-	if nThreads < 1 || threadQueueSize < 1 {
+func NewRoundRobinExecutor(nThreads, threadQueueSize int, opts ...Option) (*RoundRobinExecutor, error) {
+	o := newOptions(opts)
+	if nThreads < 1 || threadQueueSize < 1 || o.capacity < 0 {
 		return nil, errors.New("executor: invalid argument")
 	}
 
+	if o.aging != nil {
+		return nil, ErrUnsupportedOption
+	}
+
 	ids := ring.New(nThreads)
 	for i := 1; i <= nThreads; i++ {
 		ids.Value = i
 		ids = ids.Next()
 	}
 
-	channels := make(map[int]chan concurrent.Runner, nThreads)
-	shutdown := make(map[int]chan struct{}, nThreads)
-	wg := &sync.WaitGroup{}
-	wg.Add(nThreads)
+	e := &RoundRobinExecutor{
+		mutex:     &sync.Mutex{},
+		ids:       ids,
+		channels:  make(map[int]chan concurrent.Runner, nThreads),
+		shutdown:  make(map[int]chan struct{}, nThreads),
+		completed: make(map[int]*uint64, nThreads),
+		queueSize: threadQueueSize,
+		wg:        &sync.WaitGroup{},
+		sending:   &sync.WaitGroup{},
+		options:   o,
+		counters:  &counters{},
+	}
+
+	if o.capacity > 0 {
+		e.slots = make(chan struct{}, o.capacity)
+	}
 
 	for i := 1; i <= nThreads; i++ {
-		channels[i] = make(chan concurrent.Runner, threadQueueSize)
-		shutdown[i] = make(chan struct{})
+		e.spawn(i)
+	}
 
-		go func(id int, runners <-chan concurrent.Runner, shutdown <-chan struct{}, wg *sync.WaitGroup) {
-			runtime.LockOSThread()
+	return e, nil
+}
 
-			for {
-				select {
-				case runner := <-runners:
-					runner.Run()
-				case <-shutdown:
-					runtime.UnlockOSThread()
-					wg.Done()
-					return
-				}
-			}
-		}(i, channels[i], shutdown[i], wg)
+// NewLeastLoadedExecutor creates a new executor like NewRoundRobinExecutor, but instead of passing runners to threads
+// in turn, it passes each runner to the thread whose queue currently has the fewest runners.
+// So a thread busy with a slow runner does not back up the runners, while other threads are idle.
+// Threads with equally loaded queues are chosen in a round robin fashion.
+func NewLeastLoadedExecutor(nThreads, queueSize int, opts ...Option) (*RoundRobinExecutor, error) {
+	e, err := NewRoundRobinExecutor(nThreads, queueSize, opts...)
+	if err != nil {
+		return nil, err
 	}
 
-	return &RoundRobinExecutor{mutex: &sync.Mutex{}, ids: ids, channels: channels, shutdown: shutdown, wg: wg}, nil
+	e.least = true
+	return e, nil
 }
 
 // Execute sends a runner instance to a specific thread for execution.
 // Runner instances should provide a mechanism to determine whether a runner passed to executor executed successfully or not.
-func (e *RoundRobinExecutor) Execute(runner concurrent.Runner) {
+// If the executor is already shutdown, the runner is not executed and ErrExecutorShutdown is returned.
+// If the queue of the thread is full, or the executor is at its capacity, the rejection policy is consulted if set,
+// otherwise it blocks.
+func (e *RoundRobinExecutor) Execute(runner concurrent.Runner) error {
+	_, err := e.execute(runner)
+	return err
+}
+
+// execute is like Execute, but also reports whether the runner is queued,
+// rather than rejected or not executed because the executor is shutdown.
+func (e *RoundRobinExecutor) execute(runner concurrent.Runner) (bool, error) {
+	if !e.acquire() {
+		return false, e.options.rejection.Reject(runner)
+	}
+
 	e.mutex.Lock()
-	defer e.mutex.Unlock()
+	if e.down {
+		e.mutex.Unlock()
+		e.release()
+		return false, ErrExecutorShutdown
+	}
 
-	id := e.ids.Value.(int)
-	e.channels[id] <- runner
-	e.ids = e.ids.Next()
+	id := e.next()
+	atomic.AddUint64(&e.counters.submitted, 1)
+	queued := e.options.queue(e.channels[id], runner)
+	e.mutex.Unlock()
+
+	if !queued {
+		atomic.AddUint64(&e.counters.submitted, ^uint64(0))
+		e.release()
+		return false, e.options.rejection.Reject(runner)
+	}
+
+	return true, nil
+}
+
+// ExecuteNamed is like Execute, but tags the runner with a name for tracing.
+// After the runner is executed, the hook set by OnTaskComplete is called with the name and duration of the runner.
+func (e *RoundRobinExecutor) ExecuteNamed(name string, runner concurrent.Runner) error {
+	return e.Execute(e.options.named(name, runner))
+}
+
+// ExecuteWait sends a runner instance to a specific thread for execution, blocking while the queue of thread is full.
+// Unlike Execute, the rejection policy is not consulted, and waiting for capacity is aborted when the context is done,
+// returning the error of context. If the executor is already shutdown, ErrExecutorShutdown is returned.
+func (e *RoundRobinExecutor) ExecuteWait(ctx context.Context, runner concurrent.Runner) error {
+	if err := e.acquireContext(ctx); err != nil {
+		return err
+	}
+
+	e.mutex.Lock()
+	if e.down {
+		e.mutex.Unlock()
+		e.release()
+		return ErrExecutorShutdown
+	}
+
+	if err := ctx.Err(); err != nil {
+		e.mutex.Unlock()
+		e.release()
+		return err
+	}
+
+	// The executor is not locked while waiting for the queue, so other callers are not blocked meanwhile.
+	// Shutdown and Resize wait for the pending sends, before signalling threads to stop.
+	c := e.channels[e.next()]
+	atomic.AddUint64(&e.counters.submitted, 1)
+	e.sending.Add(1)
+	e.mutex.Unlock()
+	defer e.sending.Done()
+
+	select {
+	case c <- runner:
+		return nil
+	case <-ctx.Done():
+		atomic.AddUint64(&e.counters.submitted, ^uint64(0))
+		e.release()
+		return ctx.Err()
+	}
+}
+
+// ExecuteAllAndWait executes all the runner instances passed and blocks until all of them are completed.
+// If a runner can not be executed, it waits for the runners already executed and returns the error.
+// A runner rejected without an error, like by DiscardPolicy, is not waited for.
+func (e *RoundRobinExecutor) ExecuteAllAndWait(runners []concurrent.Runner) error {
+	wg := &sync.WaitGroup{}
+	for _, runner := range runners {
+		wg.Add(1)
+		w := &waitingRunner{runner: runner, wg: wg, once: &sync.Once{}}
+		queued, err := e.execute(w)
+		if !queued {
+			// The rejected runner is either already run by the rejection policy, or dropped.
+			w.done()
+		}
+
+		if err != nil {
+			wg.Wait()
+			return err
+		}
+	}
+
+	wg.Wait()
+	return nil
 }
 
 // Shutdown sends shutdown signal to all threads to stop execution.
+// Each thread executes the runners already queued before stopping. Calling it more than once has no effect.
 func (e *RoundRobinExecutor) Shutdown() {
 	e.mutex.Lock()
 	defer e.mutex.Unlock()
 
+	if e.down {
+		return
+	}
+	e.down = true
+	e.sending.Wait()
+
+	// Closing the channels does not wait for the threads busy with a runner, so the executor is not kept locked.
+	for _, c := range e.shutdown {
+		close(c)
+	}
+}
+
+// ShutdownContext sends shutdown signal to all threads, and waits for them to stop execution until the context is done.
+// It returns nil if all threads stopped, otherwise the error of context, while threads keep executing
+// their queued runners in the background.
+func (e *RoundRobinExecutor) ShutdownContext(ctx context.Context) error {
+	terminated := make(chan struct{})
+	go func() {
+		e.Shutdown()
+		e.wg.Wait()
+		close(terminated)
+	}()
+
+	select {
+	case <-terminated:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// ShutdownNow sends shutdown signal to all threads to stop execution after their current runner,
+// and returns the queued runners that are not executed. Calling it more than once returns no runners.
+func (e *RoundRobinExecutor) ShutdownNow() []concurrent.Runner {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	runners := make([]concurrent.Runner, 0)
+	if e.down {
+		return runners
+	}
+	e.down = true
+	e.sending.Wait()
+
+	for id := 1; id <= len(e.channels); id++ {
+	drain:
+		for {
+			select {
+			case runner := <-e.channels[id]:
+				if s, ok := runner.(*sentinel); ok {
+					s.Run()
+					continue
+				}
+
+				runners = append(runners, runner)
+			default:
+				break drain
+			}
+		}
+	}
+
+	if len(runners) > 0 {
+		atomic.AddUint64(&e.counters.submitted, ^uint64(len(runners)-1))
+	}
+
+	for range runners {
+		e.release()
+	}
+
 	for _, c := range e.shutdown {
-		c <- struct{}{}
+		close(c)
 	}
+
+	return runners
+}
+
+// Drain blocks until all the runners queued before calling it are executed, without shutting down the executor.
+// A sentinel is queued to each thread, so the runners queued after calling it may also be executed before it returns.
+// If the executor is already shutdown, it waits for the threads to stop, which execute their queued runners before.
+func (e *RoundRobinExecutor) Drain() {
+	e.mutex.Lock()
+	if e.down {
+		e.mutex.Unlock()
+		e.wg.Wait()
+		return
+	}
+
+	wg := &sync.WaitGroup{}
+	wg.Add(len(e.channels))
+	for _, c := range e.channels {
+		c <- &sentinel{wg: wg}
+	}
+	e.mutex.Unlock()
+
+	wg.Wait()
 }
 
 // AwaitTermination awaits on executor threads to stop execution.
+// It returns only after all threads executed their queued runners and exited.
 func (e *RoundRobinExecutor) AwaitTermination() {
 	e.wg.Wait()
 }
+
+// AwaitTerminationTimeout awaits on executor threads to stop execution, at most for the duration provided.
+// It returns true if all threads stopped within the duration, otherwise false.
+func (e *RoundRobinExecutor) AwaitTerminationTimeout(d time.Duration) bool {
+	return waitTimeout(e.wg, d)
+}
+
+// Resize changes the number of threads in executor to nThreads.
+// Growing the executor starts new threads. Shrinking it signals the extra threads to stop after executing
+// their queued runners, so no runner is lost. It is safe to be called while runners are being executed.
+func (e *RoundRobinExecutor) Resize(nThreads int) error {
+	if nThreads < 1 {
+		return errors.New("executor: invalid argument")
+	}
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if e.down {
+		return ErrExecutorShutdown
+	}
+
+	current := len(e.channels)
+	for id := current + 1; id <= nThreads; id++ {
+		e.spawn(id)
+	}
+
+	if nThreads < current {
+		e.sending.Wait()
+	}
+
+	for id := nThreads + 1; id <= current; id++ {
+		close(e.shutdown[id])
+		delete(e.shutdown, id)
+		delete(e.channels, id)
+		delete(e.completed, id)
+	}
+
+	next := e.ids.Value.(int)
+	if next > nThreads {
+		next = 1
+	}
+
+	ids := ring.New(nThreads)
+	for i := 0; i < nThreads; i++ {
+		ids.Value = (next-1+i)%nThreads + 1
+		ids = ids.Next()
+	}
+	e.ids = ids
+
+	return nil
+}
+
+// Stats returns the statistics of the executor, read without locking the executor.
+func (e *RoundRobinExecutor) Stats() Stats {
+	submitted := atomic.LoadUint64(&e.counters.submitted)
+	completed := atomic.LoadUint64(&e.counters.completed)
+	active := atomic.LoadUint64(&e.counters.active)
+
+	var queued uint64
+	if submitted > completed+active {
+		queued = submitted - completed - active
+	}
+
+	return Stats{Submitted: submitted, Completed: completed, Queued: queued, Active: active}
+}
+
+// next returns the id of the thread to pass the next runner to. It should be called while the executor is locked.
+func (e *RoundRobinExecutor) next() int {
+	id := e.ids.Value.(int)
+	e.ids = e.ids.Next()
+	if !e.least {
+		return id
+	}
+
+	e.ids.Prev().Do(func(v interface{}) {
+		if candidate := v.(int); len(e.channels[candidate]) < len(e.channels[id]) {
+			id = candidate
+		}
+	})
+
+	return id
+}
+
+// WorkerStats returns the statistics of each thread of the executor ordered by id,
+// revealing the imbalance of load between threads that Stats hides.
+func (e *RoundRobinExecutor) WorkerStats() []WorkerStat {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	stats := make([]WorkerStat, 0, len(e.channels))
+	for id := 1; id <= len(e.channels); id++ {
+		stats = append(stats, WorkerStat{
+			ID:        id,
+			Completed: atomic.LoadUint64(e.completed[id]),
+			Queued:    len(e.channels[id]),
+		})
+	}
+
+	return stats
+}
+
+// spawn creates the queue of a thread with the id provided and starts the thread.
+func (e *RoundRobinExecutor) spawn(id int) {
+	e.channels[id] = make(chan concurrent.Runner, e.queueSize)
+	e.shutdown[id] = make(chan struct{})
+	e.completed[id] = new(uint64)
+
+	e.wg.Add(1)
+	go e.work(e.channels[id], e.shutdown[id], e.completed[id])
+}
+
+// work executes the runners of a thread until the shutdown signal is received and the queue of thread is empty.
+// The number of runners executed by the thread is counted by completed.
+func (e *RoundRobinExecutor) work(runners <-chan concurrent.Runner, shutdown <-chan struct{}, completed *uint64) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	defer e.wg.Done()
+
+	for {
+		select {
+		case runner := <-runners:
+			e.run(runner, completed)
+		case <-shutdown:
+			for {
+				select {
+				case runner := <-runners:
+					e.run(runner, completed)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// run runs the runner, updating the counters of the executor and the completed counter of the thread.
+func (e *RoundRobinExecutor) run(runner concurrent.Runner, completed *uint64) {
+	if s, ok := runner.(*sentinel); ok {
+		s.Run()
+		return
+	}
+
+	atomic.AddUint64(&e.counters.active, 1)
+	e.options.run(runner)
+	atomic.AddUint64(&e.counters.active, ^uint64(0))
+	atomic.AddUint64(&e.counters.completed, 1)
+	atomic.AddUint64(completed, 1)
+	e.release()
+}
+
+// acquire takes a slot of the executor capacity and reports whether it is taken.
+// If a rejection policy is set, it does not block when the executor is at capacity.
+func (e *RoundRobinExecutor) acquire() bool {
+	if e.slots == nil {
+		return true
+	}
+
+	if e.options.rejection == nil {
+		e.slots <- struct{}{}
+		return true
+	}
+
+	select {
+	case e.slots <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// acquireContext takes a slot of the executor capacity, blocking until a slot is released or the context is done.
+func (e *RoundRobinExecutor) acquireContext(ctx context.Context) error {
+	if e.slots == nil {
+		return nil
+	}
+
+	select {
+	case e.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// release gives back a slot of the executor capacity.
+func (e *RoundRobinExecutor) release() {
+	if e.slots != nil {
+		<-e.slots
+	}
+}
+
+// waitingRunner is a runner wrapper that marks a wait group as done when the wrapped runner is completed.
+type waitingRunner struct {
+	runner concurrent.Runner
+	wg     *sync.WaitGroup
+	once   *sync.Once
+}
+
+// Run runs the wrapped runner and then marks the wait group as done.
+func (r *waitingRunner) Run() {
+	defer r.done()
+	r.runner.Run()
+}
+
+// done marks the wait group as done, only once.
+func (r *waitingRunner) done() {
+	r.once.Do(r.wg.Done)
+}
+
+// sentinel is a runner queued by Drain, which is not counted in statistics and marks a wait group as done when reached.
+type sentinel struct {
+	wg *sync.WaitGroup
+}
+
+// Run marks the wait group as done.
+func (s *sentinel) Run() {
+	s.wg.Done()
+}
+
+// waitTimeout waits on the wait group at most for the duration provided, and reports whether the wait is completed.
+func waitTimeout(wg *sync.WaitGroup, d time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-done:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
@@ -0,0 +1,704 @@
+package executor
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lireza/lib/concurrent"
+)
+
+// runnerFunc is a function implementing concurrent.Runner.
+type runnerFunc func()
+
+func (f runnerFunc) Run() {
+	f()
+}
+
+// counting returns a runner incrementing n.
+func counting(n *int64) concurrent.Runner {
+	return runnerFunc(func() { atomic.AddInt64(n, 1) })
+}
+
+func TestExecuteAllAndWait(t *testing.T) {
+	e, _ := NewRoundRobinExecutor(3, 2)
+
+	var n int64
+	runners := make([]concurrent.Runner, 20)
+	for i := range runners {
+		runners[i] = counting(&n)
+	}
+
+	if err := e.ExecuteAllAndWait(runners); err != nil {
+		t.Fatal(err)
+	}
+
+	if n != 20 {
+		t.Errorf("executed = %d, want 20", n)
+	}
+}
+
+// blocker is a runner that reports when it is started, and blocks until it is released.
+type blocker struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func newBlocker() *blocker {
+	return &blocker{started: make(chan struct{}), release: make(chan struct{})}
+}
+
+func (b *blocker) Run() {
+	close(b.started)
+	<-b.release
+}
+
+// block executes a blocker on each of the n threads of the executor, and waits for them to start.
+func block(t *testing.T, e *RoundRobinExecutor, n int) []*blocker {
+	t.Helper()
+
+	blockers := make([]*blocker, n)
+	for i := range blockers {
+		blockers[i] = newBlocker()
+		if err := e.Execute(blockers[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, b := range blockers {
+		<-b.started
+	}
+
+	return blockers
+}
+
+func unblock(blockers []*blocker) {
+	for _, b := range blockers {
+		close(b.release)
+	}
+}
+
+func TestRoundRobinExecutorExecutesQueuedRunners(t *testing.T) {
+	e, err := NewRoundRobinExecutor(4, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var n int64
+	for i := 0; i < 100; i++ {
+		if err := e.Execute(counting(&n)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	e.Shutdown()
+	e.AwaitTermination()
+	e.AwaitTermination()
+
+	if n != 100 {
+		t.Errorf("executed = %d, want 100", n)
+	}
+}
+
+func TestNewRoundRobinExecutorInvalidArgument(t *testing.T) {
+	tests := []struct {
+		nThreads, queueSize int
+		opts                []Option
+	}{
+		{0, 1, nil},
+		{1, 0, nil},
+		{1, 1, []Option{WithCapacity(-1)}},
+	}
+
+	for _, test := range tests {
+		if _, err := NewRoundRobinExecutor(test.nThreads, test.queueSize, test.opts...); err == nil {
+			t.Errorf("NewRoundRobinExecutor(%d, %d) succeeded, want an error", test.nThreads, test.queueSize)
+		}
+	}
+}
+
+func TestAwaitTerminationTimeout(t *testing.T) {
+	e, _ := NewRoundRobinExecutor(1, 1)
+	blockers := block(t, e, 1)
+	e.Shutdown()
+
+	if e.AwaitTerminationTimeout(20 * time.Millisecond) {
+		t.Error("AwaitTerminationTimeout = true while a runner is blocked")
+	}
+
+	unblock(blockers)
+	if !e.AwaitTerminationTimeout(time.Second) {
+		t.Error("AwaitTerminationTimeout = false after the runner is released")
+	}
+}
+
+func TestShutdownNow(t *testing.T) {
+	e, _ := NewRoundRobinExecutor(1, 4)
+	blockers := block(t, e, 1)
+
+	var n int64
+	for i := 0; i < 3; i++ {
+		_ = e.Execute(counting(&n))
+	}
+
+	runners := e.ShutdownNow()
+	if len(runners) != 3 {
+		t.Errorf("ShutdownNow returned %d runners, want 3", len(runners))
+	}
+
+	if again := e.ShutdownNow(); len(again) != 0 {
+		t.Errorf("second ShutdownNow returned %d runners, want 0", len(again))
+	}
+
+	unblock(blockers)
+	e.AwaitTermination()
+
+	if n != 0 {
+		t.Errorf("executed = %d of the returned runners, want 0", n)
+	}
+
+	if s := e.Stats(); s.Submitted != 1 || s.Completed != 1 {
+		t.Errorf("Stats = %+v, want 1 submitted and completed", s)
+	}
+}
+
+func TestExecuteAfterShutdown(t *testing.T) {
+	e, _ := NewRoundRobinExecutor(2, 2)
+	e.Shutdown()
+	e.Shutdown()
+
+	var n int64
+	if err := e.Execute(counting(&n)); err != ErrExecutorShutdown {
+		t.Errorf("Execute = %v, want ErrExecutorShutdown", err)
+	}
+
+	if err := e.ExecuteWait(context.Background(), counting(&n)); err != ErrExecutorShutdown {
+		t.Errorf("ExecuteWait = %v, want ErrExecutorShutdown", err)
+	}
+
+	if err := e.ExecuteAllAndWait([]concurrent.Runner{counting(&n)}); err != ErrExecutorShutdown {
+		t.Errorf("ExecuteAllAndWait = %v, want ErrExecutorShutdown", err)
+	}
+
+	e.AwaitTermination()
+	if n != 0 {
+		t.Errorf("executed = %d, want 0", n)
+	}
+}
+
+func TestPanicRecovery(t *testing.T) {
+	recovered := make(chan interface{}, 1)
+	e, _ := NewRoundRobinExecutor(1, 2, WithPanicHandler(func(r interface{}) { recovered <- r }))
+
+	var n int64
+	_ = e.Execute(runnerFunc(func() { panic("failure") }))
+	_ = e.Execute(counting(&n))
+	e.Shutdown()
+	e.AwaitTermination()
+
+	if r := <-recovered; r != "failure" {
+		t.Errorf("recovered = %v, want failure", r)
+	}
+
+	if n != 1 {
+		t.Errorf("executed after panic = %d, want 1", n)
+	}
+
+	if s := e.Stats(); s.Completed != 2 {
+		t.Errorf("Completed = %d, want 2 including the panicked runner", s.Completed)
+	}
+}
+
+func TestExecuteAllAndWaitDiscarded(t *testing.T) {
+	e, _ := NewRoundRobinExecutor(1, 1, WithRejectionPolicy(DiscardPolicy{}))
+	blockers := block(t, e, 1)
+
+	var n int64
+	done := make(chan error, 1)
+	go func() {
+		done <- e.ExecuteAllAndWait([]concurrent.Runner{counting(&n), counting(&n), counting(&n)})
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	unblock(blockers)
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Error(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("ExecuteAllAndWait waits for the discarded runners")
+	}
+
+	if n != 1 {
+		t.Errorf("executed = %d, want 1 queued runner", n)
+	}
+}
+
+func TestStats(t *testing.T) {
+	e, _ := NewRoundRobinExecutor(2, 4)
+	blockers := block(t, e, 2)
+
+	var n int64
+	for i := 0; i < 4; i++ {
+		_ = e.Execute(counting(&n))
+	}
+
+	want := Stats{Submitted: 6, Active: 2, Queued: 4}
+	if s := e.Stats(); s != want {
+		t.Errorf("Stats = %+v, want %+v", s, want)
+	}
+
+	unblock(blockers)
+	e.Shutdown()
+	e.AwaitTermination()
+
+	want = Stats{Submitted: 6, Completed: 6}
+	if s := e.Stats(); s != want {
+		t.Errorf("Stats = %+v, want %+v", s, want)
+	}
+}
+
+func TestResizeUnderLoad(t *testing.T) {
+	tests := []struct {
+		from, to int
+	}{
+		{2, 4},
+		{4, 2},
+	}
+
+	for _, test := range tests {
+		e, _ := NewRoundRobinExecutor(test.from, 4)
+
+		var n, submitted int64
+		stop := make(chan struct{})
+		wg := &sync.WaitGroup{}
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for {
+					select {
+					case <-stop:
+						return
+					default:
+					}
+
+					if err := e.Execute(counting(&n)); err != nil {
+						t.Error(err)
+						return
+					}
+					atomic.AddInt64(&submitted, 1)
+				}
+			}()
+		}
+
+		time.Sleep(10 * time.Millisecond)
+		if err := e.Resize(test.to); err != nil {
+			t.Fatal(err)
+		}
+
+		time.Sleep(10 * time.Millisecond)
+		close(stop)
+		wg.Wait()
+
+		if stats := e.WorkerStats(); len(stats) != test.to {
+			t.Errorf("threads after Resize(%d) = %d", test.to, len(stats))
+		}
+
+		e.Shutdown()
+		e.AwaitTermination()
+
+		if n != submitted {
+			t.Errorf("resize %d to %d: executed = %d, want %d", test.from, test.to, n, submitted)
+		}
+
+		if err := e.Resize(test.from); err != ErrExecutorShutdown {
+			t.Errorf("Resize after Shutdown = %v, want ErrExecutorShutdown", err)
+		}
+	}
+
+	e, _ := NewRoundRobinExecutor(1, 1)
+	defer e.Shutdown()
+
+	if err := e.Resize(0); err == nil {
+		t.Error("Resize(0) expected error")
+	}
+}
+
+func TestShutdownContext(t *testing.T) {
+	e, _ := NewRoundRobinExecutor(1, 1)
+	blockers := block(t, e, 1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if err := e.ShutdownContext(ctx); err != context.DeadlineExceeded {
+		t.Errorf("ShutdownContext = %v, want context.DeadlineExceeded", err)
+	}
+
+	if err := e.Execute(runnerFunc(func() {})); err != ErrExecutorShutdown {
+		t.Errorf("Execute = %v, want ErrExecutorShutdown", err)
+	}
+
+	unblock(blockers)
+	if err := e.ShutdownContext(context.Background()); err != nil {
+		t.Errorf("ShutdownContext = %v, want nil", err)
+	}
+}
+
+func TestExecuteWait(t *testing.T) {
+	e, _ := NewRoundRobinExecutor(1, 1)
+	blockers := block(t, e, 1)
+
+	var n int64
+	if err := e.ExecuteWait(context.Background(), counting(&n)); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if err := e.ExecuteWait(ctx, counting(&n)); err != context.DeadlineExceeded {
+		t.Errorf("ExecuteWait on full queue = %v, want context.DeadlineExceeded", err)
+	}
+
+	if s := e.Stats(); s.Submitted != 2 {
+		t.Errorf("Submitted = %d, want 2 excluding the expired runner", s.Submitted)
+	}
+
+	waiting := make(chan error, 1)
+	go func() { waiting <- e.ExecuteWait(context.Background(), counting(&n)) }()
+
+	// Another caller is not blocked by the waiting one.
+	stats := make(chan []WorkerStat, 1)
+	go func() { stats <- e.WorkerStats() }()
+	select {
+	case <-stats:
+	case <-time.After(time.Second):
+		t.Fatal("WorkerStats is blocked by ExecuteWait")
+	}
+
+	unblock(blockers)
+	select {
+	case err := <-waiting:
+		if err != nil {
+			t.Error(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("ExecuteWait is not returned after the queue has space")
+	}
+
+	e.Shutdown()
+	e.AwaitTermination()
+	if n != 2 {
+		t.Errorf("executed = %d, want 2", n)
+	}
+}
+
+func TestExecuteWaitRacingShutdown(t *testing.T) {
+	for i := 0; i < 50; i++ {
+		e, _ := NewRoundRobinExecutor(2, 1)
+
+		var n, accepted int64
+		wg := &sync.WaitGroup{}
+		for j := 0; j < 8; j++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if e.ExecuteWait(context.Background(), counting(&n)) == nil {
+					atomic.AddInt64(&accepted, 1)
+				}
+			}()
+		}
+
+		e.Shutdown()
+		wg.Wait()
+		e.AwaitTermination()
+
+		if n != accepted {
+			t.Fatalf("executed = %d, want %d accepted runners", n, accepted)
+		}
+	}
+}
+
+func TestExecuteNamed(t *testing.T) {
+	type completion struct {
+		name string
+		d    time.Duration
+	}
+
+	completions := make(chan completion, 2)
+	e, _ := NewRoundRobinExecutor(1, 2, OnTaskComplete(func(name string, d time.Duration) {
+		completions <- completion{name, d}
+	}), WithPanicHandler(func(interface{}) {}))
+
+	_ = e.ExecuteNamed("sleep", runnerFunc(func() { time.Sleep(10 * time.Millisecond) }))
+	_ = e.ExecuteNamed("panic", runnerFunc(func() { panic("failure") }))
+	_ = e.Execute(runnerFunc(func() {}))
+	e.Shutdown()
+	e.AwaitTermination()
+	close(completions)
+
+	c := <-completions
+	if c.name != "sleep" || c.d < 10*time.Millisecond {
+		t.Errorf("completion = %+v, want sleep with at least 10ms", c)
+	}
+
+	if c := <-completions; c.name != "panic" {
+		t.Errorf("completion = %+v, want panic", c)
+	}
+
+	if c, ok := <-completions; ok {
+		t.Errorf("completion = %+v of an unnamed runner, want none", c)
+	}
+}
+
+func TestLeastLoadedExecutor(t *testing.T) {
+	e, err := NewLeastLoadedExecutor(2, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	blockers := block(t, e, 2)
+
+	var n int64
+	done := make(chan struct{}, 2)
+	signalling := runnerFunc(func() {
+		atomic.AddInt64(&n, 1)
+		done <- struct{}{}
+	})
+
+	_ = e.Execute(signalling)
+	_ = e.Execute(signalling)
+
+	// Thread 2 empties its queue while thread 1 is still blocked, so the next runner is passed to thread 2,
+	// although it is the turn of thread 1 in a round robin fashion.
+	unblock(blockers[1:])
+	<-done
+
+	_ = e.Execute(signalling)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("runner is passed to the blocked thread")
+	}
+
+	if stats := e.WorkerStats(); stats[0].Queued != 1 {
+		t.Errorf("queued on thread 1 = %d, want 1", stats[0].Queued)
+	}
+
+	unblock(blockers[:1])
+	e.Shutdown()
+	e.AwaitTermination()
+	if n != 3 {
+		t.Errorf("executed = %d, want 3", n)
+	}
+}
+
+func TestDrain(t *testing.T) {
+	e, _ := NewRoundRobinExecutor(3, 10)
+
+	var n int64
+	for i := 0; i < 20; i++ {
+		_ = e.Execute(runnerFunc(func() {
+			time.Sleep(time.Millisecond)
+			atomic.AddInt64(&n, 1)
+		}))
+	}
+
+	e.Drain()
+	if n := atomic.LoadInt64(&n); n != 20 {
+		t.Errorf("executed after Drain = %d, want 20", n)
+	}
+
+	if s := e.Stats(); s.Submitted != 20 || s.Completed != 20 {
+		t.Errorf("Stats = %+v, want 20 submitted and completed excluding sentinels", s)
+	}
+
+	if err := e.Execute(counting(&n)); err != nil {
+		t.Errorf("Execute after Drain = %v, want nil", err)
+	}
+
+	e.Shutdown()
+	e.Drain()
+	if n != 21 {
+		t.Errorf("executed = %d, want 21", n)
+	}
+}
+
+func TestWithCapacity(t *testing.T) {
+	e, _ := NewRoundRobinExecutor(2, 10, WithCapacity(3), WithRejectionPolicy(AbortPolicy{}))
+	blockers := block(t, e, 2)
+
+	var n int64
+	if err := e.Execute(counting(&n)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := e.Execute(counting(&n)); err != ErrRejected {
+		t.Errorf("Execute at capacity = %v, want ErrRejected", err)
+	}
+
+	unblock(blockers)
+	e.Drain()
+	if err := e.Execute(counting(&n)); err != nil {
+		t.Errorf("Execute after slots are released = %v, want nil", err)
+	}
+
+	e.Shutdown()
+	e.AwaitTermination()
+	if n != 2 {
+		t.Errorf("executed = %d, want 2", n)
+	}
+}
+
+func TestWithCapacityBlocks(t *testing.T) {
+	e, _ := NewRoundRobinExecutor(2, 10, WithCapacity(2))
+	blockers := block(t, e, 2)
+
+	var n int64
+	queued := make(chan error, 1)
+	go func() { queued <- e.Execute(counting(&n)) }()
+
+	select {
+	case <-queued:
+		t.Fatal("Execute at capacity is not blocked")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := e.ExecuteWait(ctx, counting(&n)); err != context.DeadlineExceeded {
+		t.Errorf("ExecuteWait at capacity = %v, want context.DeadlineExceeded", err)
+	}
+
+	unblock(blockers[:1])
+	if err := <-queued; err != nil {
+		t.Error(err)
+	}
+
+	unblock(blockers[1:])
+	e.Shutdown()
+	e.AwaitTermination()
+	if n != 1 {
+		t.Errorf("executed = %d, want 1", n)
+	}
+}
+
+func TestUnsupportedOption(t *testing.T) {
+	hook := OnTaskComplete(func(string, time.Duration) {})
+	reject := WithRejectionPolicy(AbortPolicy{})
+
+	if _, err := NewFixedThreadPool(1, 1, WithCapacity(1)); err != ErrUnsupportedOption {
+		t.Errorf("NewFixedThreadPool with capacity = %v, want ErrUnsupportedOption", err)
+	}
+
+	if _, err := NewFixedThreadPool(1, 1, hook); err != ErrUnsupportedOption {
+		t.Errorf("NewFixedThreadPool with hook = %v, want ErrUnsupportedOption", err)
+	}
+
+	if _, err := NewWorkStealingExecutor(1, reject); err != ErrUnsupportedOption {
+		t.Errorf("NewWorkStealingExecutor with rejection policy = %v, want ErrUnsupportedOption", err)
+	}
+
+	if _, err := NewPriorityExecutor(1, reject); err != ErrUnsupportedOption {
+		t.Errorf("NewPriorityExecutor with rejection policy = %v, want ErrUnsupportedOption", err)
+	}
+
+	if _, err := NewScheduledExecutor(1, WithCapacity(1)); err != ErrUnsupportedOption {
+		t.Errorf("NewScheduledExecutor with capacity = %v, want ErrUnsupportedOption", err)
+	}
+
+	p, err := NewFixedThreadPool(1, 1, reject)
+	if err != nil {
+		t.Errorf("NewFixedThreadPool with rejection policy = %v, want nil", err)
+	} else {
+		p.Shutdown()
+	}
+}
+
+func TestWorkerStats(t *testing.T) {
+	e, _ := NewRoundRobinExecutor(2, 10)
+	slow := newBlocker()
+	_ = e.Execute(slow)
+	<-slow.started
+
+	done := make(chan struct{}, 10)
+	for i := 0; i < 9; i++ {
+		_ = e.Execute(runnerFunc(func() { done <- struct{}{} }))
+	}
+
+	// Thread 2 executes its 5 runners, while thread 1 is busy with the slow one.
+	for i := 0; i < 5; i++ {
+		<-done
+	}
+
+	stats := e.WorkerStats()
+	if len(stats) != 2 {
+		t.Fatalf("WorkerStats returned %d threads, want 2", len(stats))
+	}
+
+	if s := stats[0]; s.ID != 1 || s.Queued != 4 || s.Completed != 0 {
+		t.Errorf("thread 1 = %+v, want 4 queued and none completed", s)
+	}
+
+	if s := stats[1]; s.ID != 2 || s.Queued != 0 {
+		t.Errorf("thread 2 = %+v, want none queued", s)
+	}
+
+	close(slow.release)
+	e.Shutdown()
+	e.AwaitTermination()
+
+	stats = e.WorkerStats()
+	if stats[0].Completed != 5 || stats[1].Completed != 5 {
+		t.Errorf("WorkerStats = %+v, want 5 completed by each thread", stats)
+	}
+}
+
+// benchmarkSkewed executes batches of runners on the executor, in which every fourth runner is slow.
+// With four threads, the slow runners are all passed to the same thread in a round robin fashion.
+func benchmarkSkewed(b *testing.B, e Executor) {
+	const batch = 32
+
+	wg := &sync.WaitGroup{}
+	slow := runnerFunc(func() {
+		time.Sleep(200 * time.Microsecond)
+		wg.Done()
+	})
+	fast := runnerFunc(wg.Done)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		wg.Add(batch)
+		for j := 0; j < batch; j++ {
+			runner := fast
+			if j%4 == 0 {
+				runner = slow
+			}
+
+			if err := e.Execute(runner); err != nil {
+				b.Fatal(err)
+			}
+		}
+		wg.Wait()
+	}
+	b.StopTimer()
+
+	e.Shutdown()
+	e.AwaitTermination()
+}
+
+func BenchmarkRoundRobinExecutorSkewed(b *testing.B) {
+	e, _ := NewRoundRobinExecutor(4, 32)
+	benchmarkSkewed(b, e)
+}
+
+func BenchmarkLeastLoadedExecutorSkewed(b *testing.B) {
+	e, _ := NewLeastLoadedExecutor(4, 32)
+	benchmarkSkewed(b, e)
+}
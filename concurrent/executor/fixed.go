@@ -0,0 +1,120 @@
+package executor
+
+import (
+	"errors"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/lireza/lib/concurrent"
+)
+
+// FixedThreadPool is an executor implementation that contains some threads reading runners from a shared queue.
+// Unlike RoundRobinExecutor, an idle thread takes the next queued runner, so the load is balanced naturally.
+type FixedThreadPool struct {
+	mutex    *sync.Mutex
+	runners  chan concurrent.Runner
+	shutdown chan struct{}
+	wg       *sync.WaitGroup
+	down     bool
+	options  *options
+}
+
+// NewFixedThreadPool creates a new executor with a shared queue of runners.
+// The number of threads in executor is defined through nThreads and the size of queue is defined through queueSize.
+// The executor can be configured using the options provided, for example to handle panics of runners.
+// In case of errors during executor creation the error will be return.
+func NewFixedThreadPool(nThreads, queueSize int, opts ...Option) (*FixedThreadPool, error) {
+	if nThreads < 1 || queueSize < 1 {
+		return nil, errors.New("executor: invalid argument")
+	}
+
+	o := newOptions(opts)
+	if err := o.unsupported(true, false); err != nil {
+		return nil, err
+	}
+
+	p := &FixedThreadPool{
+		mutex:    &sync.Mutex{},
+		runners:  make(chan concurrent.Runner, queueSize),
+		shutdown: make(chan struct{}),
+		wg:       &sync.WaitGroup{},
+		options:  o,
+	}
+
+	p.wg.Add(nThreads)
+	for i := 1; i <= nThreads; i++ {
+		go p.work()
+	}
+
+	return p, nil
+}
+
+// Execute sends a runner instance to the shared queue, so the next idle thread executes it.
+// If the executor is already shutdown, the runner is not executed and ErrExecutorShutdown is returned.
+// If the queue is full, the rejection policy is consulted if set, otherwise it blocks.
+func (p *FixedThreadPool) Execute(runner concurrent.Runner) error {
+	p.mutex.Lock()
+	if p.down {
+		p.mutex.Unlock()
+		return ErrExecutorShutdown
+	}
+
+	queued := p.options.queue(p.runners, runner)
+	p.mutex.Unlock()
+
+	if !queued {
+		return p.options.rejection.Reject(runner)
+	}
+
+	return nil
+}
+
+// Shutdown sends shutdown signal to all threads to stop execution.
+// The runners already queued are executed before threads stop. Calling it more than once has no effect.
+func (p *FixedThreadPool) Shutdown() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.down {
+		return
+	}
+
+	p.down = true
+	close(p.shutdown)
+}
+
+// AwaitTermination awaits on executor threads to stop execution.
+// It returns only after all queued runners are executed and all threads exited.
+func (p *FixedThreadPool) AwaitTermination() {
+	p.wg.Wait()
+}
+
+// AwaitTerminationTimeout awaits on executor threads to stop execution, at most for the duration provided.
+// It returns true if all threads stopped within the duration, otherwise false.
+func (p *FixedThreadPool) AwaitTerminationTimeout(d time.Duration) bool {
+	return waitTimeout(p.wg, d)
+}
+
+// work executes the runners of the shared queue until the shutdown signal is received and the queue is empty.
+func (p *FixedThreadPool) work() {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	defer p.wg.Done()
+
+	for {
+		select {
+		case runner := <-p.runners:
+			p.options.run(runner)
+		case <-p.shutdown:
+			for {
+				select {
+				case runner := <-p.runners:
+					p.options.run(runner)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
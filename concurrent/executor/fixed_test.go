@@ -0,0 +1,35 @@
+package executor
+
+import (
+	"sync/atomic"
+	"testing"
+)
+
+func TestFixedThreadPool(t *testing.T) {
+	p, err := NewFixedThreadPool(3, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var n int64
+	for i := 0; i < 50; i++ {
+		if err := p.Execute(counting(&n)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	p.Shutdown()
+	p.AwaitTermination()
+	if atomic.LoadInt64(&n) != 50 {
+		t.Errorf("executed = %d, want 50", n)
+	}
+
+	if err := p.Execute(counting(&n)); err != ErrExecutorShutdown {
+		t.Errorf("Execute = %v, want ErrExecutorShutdown", err)
+	}
+}
+
+func BenchmarkFixedThreadPoolSkewed(b *testing.B) {
+	p, _ := NewFixedThreadPool(4, 32)
+	benchmarkSkewed(b, p)
+}
@@ -0,0 +1,154 @@
+package executor
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/lireza/lib/concurrent"
+)
+
+// ErrUnsupportedOption determines an option provided to create an executor is not supported by the executor.
+var ErrUnsupportedOption = errors.New("executor: option not supported")
+
+// Option configures an executor during creation.
+// WithCapacity and OnTaskComplete are supported only by RoundRobinExecutor, WithRejectionPolicy
+// only by RoundRobinExecutor and FixedThreadPool, and WithAging only by PriorityExecutor.
+// Other executors return ErrUnsupportedOption on creation.
+type Option func(*options)
+
+// options contains the configurations shared between executors.
+type options struct {
+	panicHandler func(interface{})
+	rejection    RejectionPolicy
+	onComplete   func(string, time.Duration)
+	capacity     int
+	aging        *aging
+}
+
+// aging is the rate a queued runner gains priority, per interval it waits.
+type aging struct {
+	rate     int
+	interval time.Duration
+}
+
+// WithPanicHandler sets the handler called with the recovered value, when a runner panics.
+// By default the recovered value is logged. In both cases the thread continues executing the next runners.
+func WithPanicHandler(handler func(interface{})) Option {
+	return func(o *options) {
+		o.panicHandler = handler
+	}
+}
+
+// WithRejectionPolicy sets the policy handling the runners that can not be queued, because the queue is full.
+func WithRejectionPolicy(policy RejectionPolicy) Option {
+	return func(o *options) {
+		o.rejection = policy
+	}
+}
+
+// OnTaskComplete sets the hook called after each named runner is executed, with the name of runner
+// and its wall-clock duration. The hook is called on the thread executing the runner, even if the runner panics.
+func OnTaskComplete(hook func(name string, d time.Duration)) Option {
+	return func(o *options) {
+		o.onComplete = hook
+	}
+}
+
+// WithCapacity limits the total number of runners outstanding in a RoundRobinExecutor, queued or being executed,
+// to n across all threads, regardless of the size of thread queues. When the executor is at capacity,
+// the rejection policy is consulted if set, otherwise it blocks. Zero means no limit, and n can not be negative.
+func WithCapacity(n int) Option {
+	return func(o *options) {
+		o.capacity = n
+	}
+}
+
+// WithAging makes the priority of a runner queued in a PriorityExecutor increase by rate for every interval
+// it waits, in proportion to the time waited. So runners with low priority are eventually executed,
+// while higher priorities are still favored in the short term.
+// The rate can not be negative and the interval must be positive.
+func WithAging(rate int, interval time.Duration) Option {
+	return func(o *options) {
+		o.aging = &aging{rate: rate, interval: interval}
+	}
+}
+
+// newOptions creates the options with default values, configured by the options provided.
+func newOptions(opts []Option) *options {
+	o := &options{
+		panicHandler: func(r interface{}) {
+			log.Printf("executor: runner panicked: %v", r)
+		},
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// unsupported returns ErrUnsupportedOption if an option supported only by RoundRobinExecutor is set,
+// the rejection policy is set while the executor does not reject runners, as reported by rejects,
+// or aging is set while the executor does not prioritize runners, as reported by ages.
+func (o *options) unsupported(rejects, ages bool) error {
+	if o.capacity != 0 || o.onComplete != nil || (o.rejection != nil && !rejects) || (o.aging != nil && !ages) {
+		return ErrUnsupportedOption
+	}
+
+	return nil
+}
+
+// queue sends the runner to the channel and reports whether it is queued.
+// If a rejection policy is set, it does not block when the channel is full.
+func (o *options) queue(c chan<- concurrent.Runner, runner concurrent.Runner) bool {
+	if o.rejection == nil {
+		c <- runner
+		return true
+	}
+
+	select {
+	case c <- runner:
+		return true
+	default:
+		return false
+	}
+}
+
+// run runs the runner and recovers it from panic using the panic handler.
+func (o *options) run(runner concurrent.Runner) {
+	defer func() {
+		if r := recover(); r != nil {
+			o.panicHandler(r)
+		}
+	}()
+
+	runner.Run()
+}
+
+// named wraps the runner to call the task complete hook with the name provided, if the hook is set.
+func (o *options) named(name string, runner concurrent.Runner) concurrent.Runner {
+	if o.onComplete == nil {
+		return runner
+	}
+
+	return &namedRunner{name: name, runner: runner, onComplete: o.onComplete}
+}
+
+// namedRunner is a runner wrapper that reports the duration of the wrapped runner to a hook, with its name.
+type namedRunner struct {
+	name       string
+	runner     concurrent.Runner
+	onComplete func(string, time.Duration)
+}
+
+// Run runs the wrapped runner and then calls the hook with its duration.
+func (r *namedRunner) Run() {
+	start := time.Now()
+	defer func() {
+		r.onComplete(r.name, time.Since(start))
+	}()
+
+	r.runner.Run()
+}
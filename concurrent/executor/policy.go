@@ -0,0 +1,42 @@
+package executor
+
+import (
+	"errors"
+
+	"github.com/lireza/lib/concurrent"
+)
+
+// ErrRejected determines the runner is rejected by the executor, because its queue is full.
+var ErrRejected = errors.New("executor: runner rejected")
+
+// RejectionPolicy handles the runners that can not be queued by an executor, because the queue is full.
+// If no policy is set, the executor blocks until the queue has space for the runner.
+type RejectionPolicy interface {
+	// Reject handles the rejected runner, and returns the error to be returned by Execute.
+	Reject(runner concurrent.Runner) error
+}
+
+// AbortPolicy is a rejection policy that returns ErrRejected.
+type AbortPolicy struct{}
+
+// Reject returns ErrRejected.
+func (AbortPolicy) Reject(concurrent.Runner) error {
+	return ErrRejected
+}
+
+// CallerRunsPolicy is a rejection policy that runs the rejected runner in the goroutine calling Execute.
+type CallerRunsPolicy struct{}
+
+// Reject runs the runner.
+func (CallerRunsPolicy) Reject(runner concurrent.Runner) error {
+	runner.Run()
+	return nil
+}
+
+// DiscardPolicy is a rejection policy that silently drops the rejected runner.
+type DiscardPolicy struct{}
+
+// Reject drops the runner.
+func (DiscardPolicy) Reject(concurrent.Runner) error {
+	return nil
+}
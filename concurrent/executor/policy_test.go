@@ -0,0 +1,71 @@
+package executor
+
+import (
+	"sync/atomic"
+	"testing"
+)
+
+func TestRejectionPolicies(t *testing.T) {
+	executors := map[string]func(RejectionPolicy) (Executor, error){
+		"RoundRobinExecutor": func(policy RejectionPolicy) (Executor, error) {
+			return NewRoundRobinExecutor(1, 1, WithRejectionPolicy(policy))
+		},
+		"FixedThreadPool": func(policy RejectionPolicy) (Executor, error) {
+			return NewFixedThreadPool(1, 1, WithRejectionPolicy(policy))
+		},
+	}
+
+	tests := []struct {
+		policy RejectionPolicy
+		err    error
+		runs   bool
+	}{
+		{AbortPolicy{}, ErrRejected, false},
+		{CallerRunsPolicy{}, nil, true},
+		{DiscardPolicy{}, nil, false},
+	}
+
+	for name, create := range executors {
+		for _, test := range tests {
+			e, err := create(test.policy)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// The thread is blocked and its queue is full, so the next runner is rejected.
+			b := newBlocker()
+			_ = e.Execute(b)
+			<-b.started
+
+			var queued, rejected int64
+			if err := e.Execute(counting(&queued)); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := e.Execute(counting(&rejected)); err != test.err {
+				t.Errorf("%s with %T: Execute on full queue = %v, want %v", name, test.policy, err, test.err)
+			}
+
+			if runs := atomic.LoadInt64(&rejected) == 1; runs != test.runs {
+				t.Errorf("%s with %T: rejected runner is run by the caller = %v, want %v", name, test.policy, runs, test.runs)
+			}
+
+			close(b.release)
+			e.Shutdown()
+			e.AwaitTermination()
+
+			if queued != 1 {
+				t.Errorf("%s with %T: queued runner executed %d times, want once", name, test.policy, queued)
+			}
+
+			want := int64(0)
+			if test.runs {
+				want = 1
+			}
+
+			if rejected != want {
+				t.Errorf("%s with %T: rejected runner executed %d times, want %d", name, test.policy, rejected, want)
+			}
+		}
+	}
+}
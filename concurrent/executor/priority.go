@@ -0,0 +1,182 @@
+package executor
+
+import (
+	"container/heap"
+	"errors"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/lireza/lib/concurrent"
+)
+
+// PriorityExecutor is an executor implementation that contains some threads, executing the runners with higher
+// priority first. Runners with equal priority are executed in the order they are passed to the executor.
+// The queue is not bounded, so Execute never blocks. Using WithAging the priority of queued runners increases
+// the longer they wait, so runners with low priority are not starved by a steady flow of higher priorities.
+type PriorityExecutor struct {
+	mutex    *sync.Mutex
+	cond     *sync.Cond
+	queue    *priorityQueue
+	sequence uint64
+	start    time.Time
+	wg       *sync.WaitGroup
+	down     bool
+	options  *options
+}
+
+// NewPriorityExecutor creates a new executor based on priority of runners.
+// The number of threads in executor is defined through nThreads.
+// The executor can be configured using the options provided, for example to handle panics of runners.
+// In case of errors during executor creation the error will be return.
+func NewPriorityExecutor(nThreads int, opts ...Option) (*PriorityExecutor, error) {
+	if nThreads < 1 {
+		return nil, errors.New("executor: invalid argument")
+	}
+
+	o := newOptions(opts)
+	if err := o.unsupported(false, true); err != nil {
+		return nil, err
+	}
+
+	if o.aging != nil && (o.aging.rate < 0 || o.aging.interval <= 0) {
+		return nil, errors.New("executor: invalid argument")
+	}
+
+	mutex := &sync.Mutex{}
+	e := &PriorityExecutor{
+		mutex:   mutex,
+		cond:    sync.NewCond(mutex),
+		queue:   &priorityQueue{},
+		start:   time.Now(),
+		wg:      &sync.WaitGroup{},
+		options: o,
+	}
+
+	e.wg.Add(nThreads)
+	for i := 1; i <= nThreads; i++ {
+		go e.work()
+	}
+
+	return e, nil
+}
+
+// Execute executes the runner with priority 0.
+// If the executor is already shutdown, the runner is not executed and ErrExecutorShutdown is returned.
+func (e *PriorityExecutor) Execute(runner concurrent.Runner) error {
+	return e.ExecuteWithPriority(runner, 0)
+}
+
+// ExecuteWithPriority queues the runner with the priority provided. Higher priority runners are executed first.
+// If the executor is already shutdown, the runner is not executed and ErrExecutorShutdown is returned.
+func (e *PriorityExecutor) ExecuteWithPriority(runner concurrent.Runner, priority int) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if e.down {
+		return ErrExecutorShutdown
+	}
+
+	e.sequence++
+	heap.Push(e.queue, &prioritized{runner: runner, priority: e.effective(priority), sequence: e.sequence})
+	e.cond.Signal()
+	return nil
+}
+
+// Shutdown sends shutdown signal to all threads to stop execution.
+// The runners already queued are executed before threads stop. Calling it more than once has no effect.
+func (e *PriorityExecutor) Shutdown() {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.down = true
+	e.cond.Broadcast()
+}
+
+// AwaitTermination awaits on executor threads to stop execution.
+// It returns only after all queued runners are executed and all threads exited.
+func (e *PriorityExecutor) AwaitTermination() {
+	e.wg.Wait()
+}
+
+// AwaitTerminationTimeout awaits on executor threads to stop execution, at most for the duration provided.
+// It returns true if all threads stopped within the duration, otherwise false.
+func (e *PriorityExecutor) AwaitTerminationTimeout(d time.Duration) bool {
+	return waitTimeout(e.wg, d)
+}
+
+// effective returns the priority of a runner queued now, on the scale of the runners queued before.
+// Since all queued runners gain priority at the same rate, a runner queued later has its priority lowered
+// by the priority runners queued since the executor is created have gained, instead of increasing the priority
+// of the runners already queued. So the order of queued runners never changes and the heap remains valid.
+func (e *PriorityExecutor) effective(priority int) float64 {
+	if e.options.aging == nil {
+		return float64(priority)
+	}
+
+	waited := float64(time.Since(e.start)) / float64(e.options.aging.interval)
+	return float64(priority) - float64(e.options.aging.rate)*waited
+}
+
+// work executes the runner with the highest priority,
+// until the shutdown signal is received and no runner is queued.
+func (e *PriorityExecutor) work() {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	defer e.wg.Done()
+
+	for {
+		e.mutex.Lock()
+		for e.queue.Len() == 0 && !e.down {
+			e.cond.Wait()
+		}
+
+		if e.queue.Len() == 0 {
+			e.mutex.Unlock()
+			return
+		}
+
+		p := heap.Pop(e.queue).(*prioritized)
+		e.mutex.Unlock()
+
+		e.options.run(p.runner)
+	}
+}
+
+// prioritized is a runner queued with its effective priority.
+type prioritized struct {
+	runner   concurrent.Runner
+	priority float64
+	sequence uint64
+}
+
+// priorityQueue is a heap of runners ordered by their priority, and their sequence of addition for equal priorities.
+type priorityQueue []*prioritized
+
+func (q priorityQueue) Len() int {
+	return len(q)
+}
+
+func (q priorityQueue) Less(i, j int) bool {
+	if q[i].priority == q[j].priority {
+		return q[i].sequence < q[j].sequence
+	}
+
+	return q[i].priority > q[j].priority
+}
+
+func (q priorityQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+}
+
+func (q *priorityQueue) Push(x interface{}) {
+	*q = append(*q, x.(*prioritized))
+}
+
+func (q *priorityQueue) Pop() interface{} {
+	old := *q
+	p := old[len(old)-1]
+	old[len(old)-1] = nil
+	*q = old[:len(old)-1]
+	return p
+}
@@ -0,0 +1,116 @@
+package executor
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPriorityExecutorOrder(t *testing.T) {
+	e, err := NewPriorityExecutor(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := newBlocker()
+	_ = e.Execute(b)
+	<-b.started
+
+	mutex := &sync.Mutex{}
+	var order []string
+	record := func(name string) runnerFunc {
+		return func() {
+			mutex.Lock()
+			order = append(order, name)
+			mutex.Unlock()
+		}
+	}
+
+	_ = e.ExecuteWithPriority(record("low"), -1)
+	_ = e.Execute(record("first"))
+	_ = e.ExecuteWithPriority(record("high"), 5)
+	_ = e.Execute(record("second"))
+
+	close(b.release)
+	e.Shutdown()
+	e.AwaitTermination()
+
+	want := []string{"high", "first", "second", "low"}
+	assertOrder(t, order, want)
+
+	if err := e.Execute(record("late")); err != ErrExecutorShutdown {
+		t.Errorf("Execute = %v, want ErrExecutorShutdown", err)
+	}
+}
+
+func TestPriorityExecutorAging(t *testing.T) {
+	e, err := NewPriorityExecutor(1, WithAging(1, 10*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := newBlocker()
+	_ = e.Execute(b)
+	<-b.started
+
+	mutex := &sync.Mutex{}
+	var order []string
+	record := func(name string) runnerFunc {
+		return func() {
+			mutex.Lock()
+			order = append(order, name)
+			mutex.Unlock()
+		}
+	}
+
+	// After waiting 60ms, low has gained at least 6, so it is ahead of mid but not of high.
+	_ = e.ExecuteWithPriority(record("low"), 0)
+	time.Sleep(60 * time.Millisecond)
+	_ = e.ExecuteWithPriority(record("mid"), 3)
+	_ = e.ExecuteWithPriority(record("high"), 1000)
+
+	close(b.release)
+	e.Shutdown()
+	e.AwaitTermination()
+
+	assertOrder(t, order, []string{"high", "low", "mid"})
+}
+
+func TestWithAgingInvalid(t *testing.T) {
+	cases := []struct {
+		rate     int
+		interval time.Duration
+	}{
+		{-1, time.Second},
+		{1, 0},
+		{1, -time.Second},
+	}
+
+	for _, c := range cases {
+		if _, err := NewPriorityExecutor(1, WithAging(c.rate, c.interval)); err == nil {
+			t.Errorf("NewPriorityExecutor with WithAging(%d, %v) expected error", c.rate, c.interval)
+		}
+	}
+
+	if _, err := NewRoundRobinExecutor(1, 1, WithAging(1, time.Second)); err != ErrUnsupportedOption {
+		t.Errorf("NewRoundRobinExecutor with aging = %v, want ErrUnsupportedOption", err)
+	}
+
+	if _, err := NewFixedThreadPool(1, 1, WithAging(1, time.Second)); err != ErrUnsupportedOption {
+		t.Errorf("NewFixedThreadPool with aging = %v, want ErrUnsupportedOption", err)
+	}
+}
+
+func assertOrder(t *testing.T, order, want []string) {
+	t.Helper()
+
+	if len(order) != len(want) {
+		t.Fatalf("order = %v, want %v", order, want)
+	}
+
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("order = %v, want %v", order, want)
+		}
+	}
+}
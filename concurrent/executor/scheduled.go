@@ -0,0 +1,282 @@
+package executor
+
+import (
+	"container/heap"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/lireza/lib/concurrent"
+)
+
+// ScheduledExecutor is an executor implementation that executes runners after a delay, or periodically.
+// Runners are kept in a time ordered queue, and are passed to a pool of threads when they are due.
+type ScheduledExecutor struct {
+	mutex    *sync.Mutex
+	tasks    *schedule
+	sequence uint64
+	wake     chan struct{}
+	stop     chan struct{}
+	stopped  chan struct{}
+	pool     *FixedThreadPool
+	down     bool
+}
+
+// NewScheduledExecutor creates a new executor capable of scheduling runners.
+// The number of threads executing the due runners is defined through nThreads.
+// The executor can be configured using the options provided, for example to handle panics of runners.
+// Due runners wait for a free thread instead of being rejected, so a periodic runner is never stopped because
+// all threads are busy. Hence a rejection policy is not supported, and ErrUnsupportedOption is returned if set.
+// In case of errors during executor creation the error will be return.
+func NewScheduledExecutor(nThreads int, opts ...Option) (*ScheduledExecutor, error) {
+	if nThreads < 1 {
+		return nil, errors.New("executor: invalid argument")
+	}
+
+	if e := newOptions(opts).unsupported(false, false); e != nil {
+		return nil, e
+	}
+
+	pool, e := NewFixedThreadPool(nThreads, nThreads, opts...)
+	if e != nil {
+		return nil, e
+	}
+
+	s := &ScheduledExecutor{
+		mutex:   &sync.Mutex{},
+		tasks:   &schedule{},
+		wake:    make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+		pool:    pool,
+	}
+
+	go s.dispatch()
+	return s, nil
+}
+
+// Execute executes the runner as soon as possible.
+// If the executor is already shutdown, the runner is not executed and ErrExecutorShutdown is returned.
+func (s *ScheduledExecutor) Execute(runner concurrent.Runner) error {
+	return s.Schedule(runner, 0)
+}
+
+// Schedule executes the runner after the delay provided.
+// If the executor is already shutdown, the runner is not executed and ErrExecutorShutdown is returned.
+func (s *ScheduledExecutor) Schedule(runner concurrent.Runner, delay time.Duration) error {
+	_, e := s.add(&task{runner: runner, at: time.Now().Add(delay)})
+	return e
+}
+
+// ScheduleAtFixedRate executes the runner after the initial delay, and then periodically every period.
+// If an execution takes longer than the period, the next execution starts late, but executions never overlap.
+// The returned cancel function stops the future executions of the runner.
+// If the executor is already shutdown, the runner is not executed and ErrExecutorShutdown is returned.
+func (s *ScheduledExecutor) ScheduleAtFixedRate(runner concurrent.Runner, initialDelay, period time.Duration) (func(), error) {
+	if period <= 0 {
+		return nil, errors.New("executor: invalid argument")
+	}
+
+	t, e := s.add(&task{runner: runner, at: time.Now().Add(initialDelay), period: period})
+	if e != nil {
+		return nil, e
+	}
+
+	return func() {
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+
+		t.cancelled = true
+		if s.down {
+			return
+		}
+
+		if t.index >= 0 && t.index < s.tasks.Len() && (*s.tasks)[t.index] == t {
+			heap.Remove(s.tasks, t.index)
+		}
+	}, nil
+}
+
+// Shutdown stops scheduling runners. The runners not due yet are discarded and periodic runners are stopped,
+// but the runners already passed to threads are executed before threads stop. Calling it more than once has no effect.
+func (s *ScheduledExecutor) Shutdown() {
+	s.mutex.Lock()
+	if s.down {
+		s.mutex.Unlock()
+		return
+	}
+
+	s.down = true
+	for _, t := range *s.tasks {
+		t.index = -1
+	}
+	s.tasks = &schedule{}
+	close(s.stop)
+	s.mutex.Unlock()
+
+	<-s.stopped
+	s.pool.Shutdown()
+}
+
+// AwaitTermination awaits on executor threads to stop execution.
+func (s *ScheduledExecutor) AwaitTermination() {
+	s.pool.AwaitTermination()
+}
+
+// AwaitTerminationTimeout awaits on executor threads to stop execution, at most for the duration provided.
+// It returns true if all threads stopped within the duration, otherwise false.
+func (s *ScheduledExecutor) AwaitTerminationTimeout(d time.Duration) bool {
+	return s.pool.AwaitTerminationTimeout(d)
+}
+
+// add adds the task to the schedule and wakes the dispatcher up to consider it.
+func (s *ScheduledExecutor) add(t *task) (*task, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.down {
+		return nil, ErrExecutorShutdown
+	}
+
+	s.sequence++
+	t.sequence = s.sequence
+	heap.Push(s.tasks, t)
+	s.notify()
+	return t, nil
+}
+
+// notify wakes the dispatcher up, without blocking if it is already notified.
+func (s *ScheduledExecutor) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// dispatch passes the due tasks to the pool until the executor is shutdown.
+func (s *ScheduledExecutor) dispatch() {
+	defer close(s.stopped)
+
+	for {
+		s.mutex.Lock()
+		var timer *time.Timer
+		var due <-chan time.Time
+		if s.tasks.Len() > 0 {
+			t := (*s.tasks)[0]
+			if wait := time.Until(t.at); wait > 0 {
+				timer = time.NewTimer(wait)
+				due = timer.C
+			} else {
+				heap.Pop(s.tasks)
+				s.mutex.Unlock()
+
+				// The pool has no rejection policy, so it blocks until a thread is free, and can only fail after
+				// Shutdown, when the due runners are discarded like the runners not due yet.
+				if t.period > 0 {
+					_ = s.pool.Execute(&periodicRunner{executor: s, task: t})
+				} else {
+					_ = s.pool.Execute(t.runner)
+				}
+				continue
+			}
+		}
+		s.mutex.Unlock()
+
+		select {
+		case <-due:
+		case <-s.wake:
+		case <-s.stop:
+			if timer != nil {
+				timer.Stop()
+			}
+			return
+		}
+
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+}
+
+// periodicRunner runs the runner of a periodic task, and then schedules its next execution if not cancelled.
+type periodicRunner struct {
+	executor *ScheduledExecutor
+	task     *task
+}
+
+// Run runs the runner of the task and schedules the next execution, unless the task is cancelled meanwhile.
+func (r *periodicRunner) Run() {
+	if r.stopped() {
+		return
+	}
+
+	defer func() {
+		s := r.executor
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+
+		if s.down || r.task.cancelled {
+			return
+		}
+
+		r.task.at = r.task.at.Add(r.task.period)
+		heap.Push(s.tasks, r.task)
+		s.notify()
+	}()
+
+	r.task.runner.Run()
+}
+
+// stopped reports whether the task is cancelled or the executor is shutdown.
+func (r *periodicRunner) stopped() bool {
+	r.executor.mutex.Lock()
+	defer r.executor.mutex.Unlock()
+
+	return r.executor.down || r.task.cancelled
+}
+
+// task is a runner scheduled to be executed at a specific time, and periodically if period is positive.
+type task struct {
+	runner    concurrent.Runner
+	at        time.Time
+	period    time.Duration
+	sequence  uint64
+	index     int
+	cancelled bool
+}
+
+// schedule is a heap of tasks ordered by their execution time, and their sequence of addition for equal times.
+type schedule []*task
+
+func (s schedule) Len() int {
+	return len(s)
+}
+
+func (s schedule) Less(i, j int) bool {
+	if s[i].at.Equal(s[j].at) {
+		return s[i].sequence < s[j].sequence
+	}
+
+	return s[i].at.Before(s[j].at)
+}
+
+func (s schedule) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+	s[i].index = i
+	s[j].index = j
+}
+
+func (s *schedule) Push(x interface{}) {
+	t := x.(*task)
+	t.index = len(*s)
+	*s = append(*s, t)
+}
+
+func (s *schedule) Pop() interface{} {
+	old := *s
+	t := old[len(old)-1]
+	old[len(old)-1] = nil
+	t.index = -1
+	*s = old[:len(old)-1]
+	return t
+}
@@ -0,0 +1,110 @@
+package executor
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSchedule(t *testing.T) {
+	s, err := NewScheduledExecutor(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	executed := make(chan time.Time, 1)
+	start := time.Now()
+	_ = s.Schedule(runnerFunc(func() { executed <- time.Now() }), 20*time.Millisecond)
+
+	select {
+	case at := <-executed:
+		if at.Sub(start) < 20*time.Millisecond {
+			t.Errorf("executed after %v, want at least 20ms", at.Sub(start))
+		}
+	case <-time.After(time.Second):
+		t.Fatal("runner is not executed")
+	}
+
+	var n int64
+	_ = s.Schedule(counting(&n), time.Hour)
+	s.Shutdown()
+	s.AwaitTermination()
+
+	if n != 0 {
+		t.Error("runner not due is executed after Shutdown")
+	}
+
+	if err := s.Execute(counting(&n)); err != ErrExecutorShutdown {
+		t.Errorf("Execute = %v, want ErrExecutorShutdown", err)
+	}
+}
+
+func TestScheduleAtFixedRate(t *testing.T) {
+	s, _ := NewScheduledExecutor(1)
+	defer s.Shutdown()
+
+	var n int64
+	cancel, err := s.ScheduleAtFixedRate(counting(&n), 0, 5*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	cancel()
+	time.Sleep(10 * time.Millisecond)
+
+	executed := atomic.LoadInt64(&n)
+	if executed < 3 {
+		t.Errorf("executed = %d, want periodic executions", executed)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	if after := atomic.LoadInt64(&n); after != executed {
+		t.Errorf("executed = %d after cancel, want %d", after, executed)
+	}
+}
+
+func TestScheduleAtFixedRateCancelAfterShutdown(t *testing.T) {
+	s, _ := NewScheduledExecutor(1)
+
+	var n int64
+	cancels := make([]func(), 3)
+	for i := range cancels {
+		cancels[i], _ = s.ScheduleAtFixedRate(counting(&n), time.Hour, time.Hour)
+	}
+
+	s.Shutdown()
+	for _, cancel := range cancels {
+		cancel()
+	}
+
+	s.AwaitTermination()
+}
+
+func TestScheduleAtFixedRateWithBusyThreads(t *testing.T) {
+	s, _ := NewScheduledExecutor(1)
+	defer s.Shutdown()
+
+	b := newBlocker()
+	_ = s.Execute(b)
+	<-b.started
+
+	var n int64
+	_, _ = s.ScheduleAtFixedRate(counting(&n), 0, 5*time.Millisecond)
+
+	// The due executions wait for the busy thread instead of being rejected, which would stop the runner.
+	time.Sleep(20 * time.Millisecond)
+	close(b.release)
+	time.Sleep(50 * time.Millisecond)
+
+	if executed := atomic.LoadInt64(&n); executed < 2 {
+		t.Errorf("executed = %d after the thread is free, want periodic executions", executed)
+	}
+}
+
+func TestNewScheduledExecutorRejectionPolicy(t *testing.T) {
+	if _, err := NewScheduledExecutor(1, WithRejectionPolicy(AbortPolicy{})); err != ErrUnsupportedOption {
+		t.Errorf("NewScheduledExecutor with rejection policy = %v, want ErrUnsupportedOption", err)
+	}
+}
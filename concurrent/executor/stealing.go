@@ -0,0 +1,187 @@
+package executor
+
+import (
+	"errors"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/lireza/lib/concurrent"
+)
+
+// WorkStealingExecutor is an executor implementation that contains some threads, each having a local queue of runners.
+// Runners are passed to threads in a round robin fashion, and an idle thread steals runners from the tail
+// of other threads' queues, so heterogeneous runners are balanced between threads.
+// The queues are not bounded, so Execute never blocks.
+type WorkStealingExecutor struct {
+	mutex   *sync.Mutex
+	cond    *sync.Cond
+	deques  []*deque
+	next    int
+	pending int
+	wg      *sync.WaitGroup
+	down    bool
+	options *options
+}
+
+// NewWorkStealingExecutor creates a new executor based on work stealing concept.
+// The number of threads in executor is defined through nThreads.
+// The executor can be configured using the options provided, for example to handle panics of runners.
+// In case of errors during executor creation the error will be return.
+func NewWorkStealingExecutor(nThreads int, opts ...Option) (*WorkStealingExecutor, error) {
+	if nThreads < 1 {
+		return nil, errors.New("executor: invalid argument")
+	}
+
+	o := newOptions(opts)
+	if err := o.unsupported(false, false); err != nil {
+		return nil, err
+	}
+
+	mutex := &sync.Mutex{}
+	e := &WorkStealingExecutor{
+		mutex:   mutex,
+		cond:    sync.NewCond(mutex),
+		deques:  make([]*deque, nThreads),
+		wg:      &sync.WaitGroup{},
+		options: o,
+	}
+
+	for i := range e.deques {
+		e.deques[i] = &deque{mutex: &sync.Mutex{}}
+	}
+
+	e.wg.Add(nThreads)
+	for i := range e.deques {
+		go e.work(i)
+	}
+
+	return e, nil
+}
+
+// Execute sends a runner instance to the queue of a specific thread for execution.
+// If the executor is already shutdown, the runner is not executed and ErrExecutorShutdown is returned.
+func (e *WorkStealingExecutor) Execute(runner concurrent.Runner) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if e.down {
+		return ErrExecutorShutdown
+	}
+
+	e.deques[e.next].pushBack(runner)
+	e.next = (e.next + 1) % len(e.deques)
+	e.pending++
+	e.cond.Signal()
+	return nil
+}
+
+// Shutdown sends shutdown signal to all threads to stop execution.
+// The runners already queued are executed before threads stop. Calling it more than once has no effect.
+func (e *WorkStealingExecutor) Shutdown() {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.down = true
+	e.cond.Broadcast()
+}
+
+// AwaitTermination awaits on executor threads to stop execution.
+// It returns only after all queued runners are executed and all threads exited.
+func (e *WorkStealingExecutor) AwaitTermination() {
+	e.wg.Wait()
+}
+
+// AwaitTerminationTimeout awaits on executor threads to stop execution, at most for the duration provided.
+// It returns true if all threads stopped within the duration, otherwise false.
+func (e *WorkStealingExecutor) AwaitTerminationTimeout(d time.Duration) bool {
+	return waitTimeout(e.wg, d)
+}
+
+// work executes the runners of the thread's own queue, or steals runners from other queues if its own queue is empty,
+// until the shutdown signal is received and no runner is queued.
+func (e *WorkStealingExecutor) work(id int) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	defer e.wg.Done()
+
+	for {
+		if runner := e.take(id); runner != nil {
+			e.options.run(runner)
+			continue
+		}
+
+		e.mutex.Lock()
+		for e.pending == 0 && !e.down {
+			e.cond.Wait()
+		}
+
+		if e.pending == 0 && e.down {
+			e.mutex.Unlock()
+			return
+		}
+		e.mutex.Unlock()
+	}
+}
+
+// take takes a runner from the head of the thread's own queue, or steals one from the tail of other queues.
+// It returns nil if all queues are empty.
+func (e *WorkStealingExecutor) take(id int) concurrent.Runner {
+	runner := e.deques[id].popFront()
+	for i := 1; runner == nil && i < len(e.deques); i++ {
+		runner = e.deques[(id+i)%len(e.deques)].popBack()
+	}
+
+	if runner != nil {
+		e.mutex.Lock()
+		e.pending--
+		e.mutex.Unlock()
+	}
+
+	return runner
+}
+
+// deque is a double ended queue of runners, safe to be used by multiple goroutines.
+type deque struct {
+	mutex   *sync.Mutex
+	runners []concurrent.Runner
+}
+
+// pushBack adds the runner to the tail of the queue.
+func (d *deque) pushBack(runner concurrent.Runner) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.runners = append(d.runners, runner)
+}
+
+// popFront removes and returns the runner at the head of the queue, or nil if the queue is empty.
+func (d *deque) popFront() concurrent.Runner {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if len(d.runners) == 0 {
+		return nil
+	}
+
+	runner := d.runners[0]
+	d.runners[0] = nil
+	d.runners = d.runners[1:]
+	return runner
+}
+
+// popBack removes and returns the runner at the tail of the queue, or nil if the queue is empty.
+func (d *deque) popBack() concurrent.Runner {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if len(d.runners) == 0 {
+		return nil
+	}
+
+	last := len(d.runners) - 1
+	runner := d.runners[last]
+	d.runners[last] = nil
+	d.runners = d.runners[:last]
+	return runner
+}
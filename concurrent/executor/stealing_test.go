@@ -0,0 +1,55 @@
+package executor
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWorkStealingExecutorExecutesOnce(t *testing.T) {
+	e, err := NewWorkStealingExecutor(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const n = 10000
+	counts := make([]int32, n)
+
+	wg := &sync.WaitGroup{}
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := g; i < n; i += 4 {
+				i := i
+				_ = e.Execute(runnerFunc(func() {
+					// Slow runners leave the other threads idle, so they steal from the busy ones.
+					if i%100 == 0 {
+						time.Sleep(time.Millisecond)
+					}
+					atomic.AddInt32(&counts[i], 1)
+				}))
+			}
+		}(g)
+	}
+
+	wg.Wait()
+	e.Shutdown()
+	e.AwaitTermination()
+
+	for i, count := range counts {
+		if count != 1 {
+			t.Fatalf("runner %d is executed %d times, want once", i, count)
+		}
+	}
+
+	if err := e.Execute(runnerFunc(func() {})); err != ErrExecutorShutdown {
+		t.Errorf("Execute = %v, want ErrExecutorShutdown", err)
+	}
+}
+
+func BenchmarkWorkStealingExecutorSkewed(b *testing.B) {
+	e, _ := NewWorkStealingExecutor(4)
+	benchmarkSkewed(b, e)
+}
@@ -0,0 +1,66 @@
+package concurrent
+
+import "sync"
+
+// TaskGroup is a group of functions whose completion can be waited on.
+// The functions can be started on new goroutines, or passed to an executor as runners.
+type TaskGroup struct {
+	wg   *sync.WaitGroup
+	once *sync.Once
+	err  error
+}
+
+// NewTaskGroup creates a new empty task group.
+func NewTaskGroup() *TaskGroup {
+	return &TaskGroup{wg: &sync.WaitGroup{}, once: &sync.Once{}}
+}
+
+// Go runs the function on a new goroutine as a member of the group.
+func (g *TaskGroup) Go(do func()) {
+	g.GoErr(func() error {
+		do()
+		return nil
+	})
+}
+
+// GoErr runs the function on a new goroutine as a member of the group.
+// The first non-nil error returned by the members is returned by WaitErr.
+func (g *TaskGroup) GoErr(do func() error) {
+	r := g.Runner(do)
+	go r.Run()
+}
+
+// Runner returns a runner of the function as a member of the group, so it can be passed to an executor.
+// The runner should be run exactly once, otherwise waiting on the group never returns.
+func (g *TaskGroup) Runner(do func() error) Runner {
+	g.wg.Add(1)
+	return &groupRunner{group: g, do: do}
+}
+
+// Wait waits until all members of the group are completed.
+func (g *TaskGroup) Wait() {
+	g.wg.Wait()
+}
+
+// WaitErr waits until all members of the group are completed, and returns the first non-nil error returned by them.
+func (g *TaskGroup) WaitErr() error {
+	g.wg.Wait()
+	return g.err
+}
+
+// groupRunner is a runner of a function as a member of a task group.
+type groupRunner struct {
+	group *TaskGroup
+	do    func() error
+}
+
+// Run runs the function, records its error if it is the first one, and marks the member as completed.
+func (r *groupRunner) Run() {
+	defer r.group.wg.Done()
+
+	if e := r.do(); e != nil {
+		r.group.once.Do(func() {
+			r.group.err = e
+		})
+	}
+}
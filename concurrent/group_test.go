@@ -0,0 +1,44 @@
+package concurrent
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+)
+
+func TestTaskGroup(t *testing.T) {
+	g := NewTaskGroup()
+
+	var n int32
+	for i := 0; i < 10; i++ {
+		g.Go(func() { atomic.AddInt32(&n, 1) })
+	}
+
+	r := g.Runner(func() error {
+		atomic.AddInt32(&n, 1)
+		return nil
+	})
+	go r.Run()
+
+	g.Wait()
+	if n != 11 {
+		t.Errorf("completed = %d, want 11", n)
+	}
+}
+
+func TestTaskGroupWaitErr(t *testing.T) {
+	g := NewTaskGroup()
+	failure := errors.New("failure")
+
+	g.GoErr(func() error { return nil })
+	g.GoErr(func() error { return failure })
+	g.GoErr(func() error { return nil })
+
+	if e := g.WaitErr(); e != failure {
+		t.Errorf("WaitErr = %v, want %v", e, failure)
+	}
+
+	if e := NewTaskGroup().WaitErr(); e != nil {
+		t.Errorf("WaitErr of empty group = %v, want nil", e)
+	}
+}
@@ -0,0 +1,66 @@
+package concurrent
+
+import (
+	"context"
+	"sync"
+)
+
+// CountDownLatch is a synchronization aid that lets goroutines wait until a number of operations are completed.
+// Goroutines completing the operations call CountDown, and waiting goroutines block in Await until the count is zero.
+type CountDownLatch struct {
+	mutex *sync.Mutex
+	count int
+	done  chan struct{}
+}
+
+// NewCountDownLatch creates a new latch with the count of n. A latch with count of zero or less is already released.
+func NewCountDownLatch(n int) *CountDownLatch {
+	l := &CountDownLatch{mutex: &sync.Mutex{}, count: n, done: make(chan struct{})}
+	if n <= 0 {
+		l.count = 0
+		close(l.done)
+	}
+
+	return l
+}
+
+// CountDown decrements the count, releasing the waiting goroutines when it reaches zero.
+// Calling it when the count is already zero has no effect.
+func (l *CountDownLatch) CountDown() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.count == 0 {
+		return
+	}
+
+	l.count--
+	if l.count == 0 {
+		close(l.done)
+	}
+}
+
+// Count returns the current count.
+func (l *CountDownLatch) Count() int {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return l.count
+}
+
+// Await blocks until the count reaches zero, returning nil, or the context is done, returning its error.
+// It returns immediately if the count is already zero.
+func (l *CountDownLatch) Await(ctx context.Context) error {
+	select {
+	case <-l.done:
+		return nil
+	default:
+	}
+
+	select {
+	case <-l.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
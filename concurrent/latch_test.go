@@ -0,0 +1,51 @@
+package concurrent
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestCountDownLatch(t *testing.T) {
+	l := NewCountDownLatch(3)
+
+	released := make(chan error, 1)
+	go func() { released <- l.Await(context.Background()) }()
+
+	for i := 0; i < 3; i++ {
+		select {
+		case <-released:
+			t.Fatalf("Await returned with count %d", l.Count())
+		default:
+		}
+
+		l.CountDown()
+	}
+
+	select {
+	case e := <-released:
+		if e != nil {
+			t.Error(e)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Await is not released at zero")
+	}
+
+	l.CountDown()
+	if n := l.Count(); n != 0 {
+		t.Errorf("Count after extra CountDown = %d, want 0", n)
+	}
+}
+
+func TestCountDownLatchAwaitContext(t *testing.T) {
+	if e := NewCountDownLatch(0).Await(context.Background()); e != nil {
+		t.Errorf("Await of released latch = %v, want nil", e)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if e := NewCountDownLatch(1).Await(ctx); e != context.DeadlineExceeded {
+		t.Errorf("Await = %v, want context.DeadlineExceeded", e)
+	}
+}
@@ -0,0 +1,82 @@
+package concurrent
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"time"
+)
+
+// RateLimiter is a token bucket rate limiter, which can be used to throttle passing runners to an executor.
+// The bucket is refilled with rate tokens per second, and holds at most burst tokens.
+type RateLimiter struct {
+	mutex  *sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter creates a new rate limiter with a full bucket.
+// The rate should be positive and the burst should be at least 1, otherwise an error is returned.
+func NewRateLimiter(rate float64, burst int) (*RateLimiter, error) {
+	if rate <= 0 || burst < 1 {
+		return nil, errors.New("concurrent: invalid argument")
+	}
+
+	return &RateLimiter{mutex: &sync.Mutex{}, rate: rate, burst: float64(burst), tokens: float64(burst), last: time.Now()}, nil
+}
+
+// Allow takes a token without blocking, and reports whether a token was available.
+func (l *RateLimiter) Allow() bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.refill(time.Now())
+	if l.tokens < 1 {
+		return false
+	}
+
+	l.tokens--
+	return true
+}
+
+// Wait takes a token, blocking until a token is available or the context is done.
+// If the context is done first, its error is returned and the token is given back.
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	if e := ctx.Err(); e != nil {
+		return e
+	}
+
+	l.mutex.Lock()
+	l.refill(time.Now())
+	l.tokens--
+	tokens := l.tokens
+	l.mutex.Unlock()
+
+	if tokens >= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(time.Duration(-tokens / l.rate * float64(time.Second)))
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		l.mutex.Lock()
+		l.tokens = math.Min(l.tokens+1, l.burst)
+		l.mutex.Unlock()
+
+		return ctx.Err()
+	}
+}
+
+// refill adds the tokens produced since the last refill to the bucket.
+func (l *RateLimiter) refill(now time.Time) {
+	elapsed := now.Sub(l.last).Seconds()
+	l.last = now
+	l.tokens = math.Min(l.tokens+elapsed*l.rate, l.burst)
+}
@@ -0,0 +1,58 @@
+package concurrent
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterAllow(t *testing.T) {
+	l, e := NewRateLimiter(1, 3)
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	for i := 0; i < 3; i++ {
+		if !l.Allow() {
+			t.Fatalf("Allow %d of burst failed", i)
+		}
+	}
+
+	if l.Allow() {
+		t.Error("Allow after burst succeeded")
+	}
+}
+
+func TestRateLimiterWait(t *testing.T) {
+	l, _ := NewRateLimiter(100, 1)
+	start := time.Now()
+	for i := 0; i < 6; i++ {
+		if e := l.Wait(context.Background()); e != nil {
+			t.Fatal(e)
+		}
+	}
+
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Errorf("6 waits at 100 per second took %v, want at least 50ms", elapsed)
+	}
+
+	slow, _ := NewRateLimiter(0.1, 1)
+	slow.Allow()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if e := slow.Wait(ctx); e != context.DeadlineExceeded {
+		t.Errorf("Wait = %v, want context.DeadlineExceeded", e)
+	}
+}
+
+func TestNewRateLimiterInvalidArgument(t *testing.T) {
+	if _, e := NewRateLimiter(0, 1); e == nil {
+		t.Error("zero rate succeeded, want an error")
+	}
+
+	if _, e := NewRateLimiter(1, 0); e == nil {
+		t.Error("zero burst succeeded, want an error")
+	}
+}
@@ -0,0 +1,43 @@
+package concurrent
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// OnceErr is like sync.Once, but for functions that may fail, like lazy initialization of a connection.
+// A successful call is cached so the function is never called again, but a failed call can be retried.
+type OnceErr struct {
+	mutex *sync.Mutex
+	done  uint32
+}
+
+// NewOnceErr creates a new OnceErr, whose function is not called yet.
+func NewOnceErr() *OnceErr {
+	return &OnceErr{mutex: &sync.Mutex{}}
+}
+
+// Do calls the function if no previous call succeeded, and returns its error.
+// Once a call returns nil, subsequent calls return nil without calling their function.
+//
+// Calls are serialized: while a function is running, concurrent callers block. If the running function succeeds,
+// they return nil without calling their function, otherwise the next one calls its function to retry.
+func (o *OnceErr) Do(do func() error) error {
+	if atomic.LoadUint32(&o.done) == 1 {
+		return nil
+	}
+
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	if o.done == 1 {
+		return nil
+	}
+
+	if e := do(); e != nil {
+		return e
+	}
+
+	atomic.StoreUint32(&o.done, 1)
+	return nil
+}
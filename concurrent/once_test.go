@@ -0,0 +1,38 @@
+package concurrent
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestOnceErr(t *testing.T) {
+	o := NewOnceErr()
+	failure := errors.New("failure")
+
+	if e := o.Do(func() error { return failure }); e != failure {
+		t.Errorf("Do = %v, want the error", e)
+	}
+
+	var calls int32
+	wg := &sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if e := o.Do(func() error { atomic.AddInt32(&calls, 1); return nil }); e != nil {
+				t.Error(e)
+			}
+		}()
+	}
+
+	wg.Wait()
+	if calls != 1 {
+		t.Errorf("calls after success = %d, want 1", calls)
+	}
+
+	if e := o.Do(func() error { return failure }); e != nil {
+		t.Errorf("Do after success = %v, want nil", e)
+	}
+}
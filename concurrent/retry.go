@@ -0,0 +1,70 @@
+package concurrent
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+)
+
+// Retry calls do until it succeeds, at most attempts times, waiting for backoff between the calls.
+// It returns nil on the first success, otherwise the error returned by the last call.
+func Retry(attempts int, backoff time.Duration, do func() error) error {
+	return RetryContext(context.Background(), attempts, backoff, do)
+}
+
+// RetryContext is like Retry, but stops waiting between the calls when the context is done, returning its error.
+func RetryContext(ctx context.Context, attempts int, backoff time.Duration, do func() error) error {
+	return retry(ctx, attempts, func(int) time.Duration { return backoff }, do)
+}
+
+// RetryExponential calls do until it succeeds, at most attempts times, waiting between the calls
+// for base and then doubling the wait after each call, up to the maximum duration. It returns nil on the first success,
+// otherwise the error returned by the last call.
+func RetryExponential(attempts int, base time.Duration, do func() error) error {
+	return RetryExponentialContext(context.Background(), attempts, base, do)
+}
+
+// RetryExponentialContext is like RetryExponential, but stops waiting between the calls when the context is done,
+// returning its error.
+func RetryExponentialContext(ctx context.Context, attempts int, base time.Duration, do func() error) error {
+	return retry(ctx, attempts, func(attempt int) time.Duration { return exponential(base, attempt) }, do)
+}
+
+// exponential returns the base doubled attempt times, saturated at the maximum duration instead of overflowing.
+func exponential(base time.Duration, attempt int) time.Duration {
+	if attempt >= 63 || base > math.MaxInt64>>uint(attempt) {
+		return math.MaxInt64
+	}
+
+	return base << uint(attempt)
+}
+
+// retry calls do at most attempts times, waiting between the calls for the duration returned by backoff,
+// which receives the number of failed attempts minus one.
+func retry(ctx context.Context, attempts int, backoff func(int) time.Duration, do func() error) error {
+	if attempts < 1 {
+		return errors.New("concurrent: invalid argument")
+	}
+
+	var e error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if e = do(); e == nil {
+			return nil
+		}
+
+		if attempt == attempts-1 {
+			break
+		}
+
+		timer := time.NewTimer(backoff(attempt))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+
+	return e
+}
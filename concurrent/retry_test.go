@@ -0,0 +1,81 @@
+package concurrent
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestRetry(t *testing.T) {
+	failure := errors.New("failure")
+
+	calls := 0
+	e := Retry(3, time.Millisecond, func() error {
+		calls++
+		if calls < 2 {
+			return failure
+		}
+
+		return nil
+	})
+
+	if e != nil || calls != 2 {
+		t.Errorf("Retry = %v after %d calls, want nil after 2", e, calls)
+	}
+
+	calls = 0
+	if e := Retry(3, time.Millisecond, func() error { calls++; return failure }); e != failure || calls != 3 {
+		t.Errorf("Retry = %v after %d calls, want the error after 3", e, calls)
+	}
+
+	if e := Retry(0, time.Millisecond, func() error { return nil }); e == nil {
+		t.Error("Retry with no attempts succeeded, want an error")
+	}
+}
+
+func TestRetryContext(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	e := RetryContext(ctx, 10, time.Hour, func() error { return errors.New("failure") })
+	if e != context.DeadlineExceeded {
+		t.Errorf("RetryContext = %v, want context.DeadlineExceeded", e)
+	}
+}
+
+func TestRetryExponential(t *testing.T) {
+	var calls []time.Time
+	_ = RetryExponential(4, 10*time.Millisecond, func() error {
+		calls = append(calls, time.Now())
+		return errors.New("failure")
+	})
+
+	if len(calls) != 4 {
+		t.Fatalf("calls = %d, want 4", len(calls))
+	}
+
+	if wait := calls[3].Sub(calls[2]); wait < 40*time.Millisecond {
+		t.Errorf("third wait = %v, want at least 40ms", wait)
+	}
+}
+
+func TestExponentialSaturates(t *testing.T) {
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{0, time.Second},
+		{3, 8 * time.Second},
+		{34, math.MaxInt64},
+		{63, math.MaxInt64},
+		{100, math.MaxInt64},
+	}
+
+	for _, test := range tests {
+		if d := exponential(time.Second, test.attempt); d != test.want {
+			t.Errorf("exponential(1s, %d) = %v, want %v", test.attempt, d, test.want)
+		}
+	}
+}
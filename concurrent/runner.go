@@ -1,6 +1,11 @@
 // Package concurrent provides some utility abstractions and functions that are used in concurrent programming.
 package concurrent
 
+import (
+	"context"
+	"time"
+)
+
 // Runner is an abstraction for an execution that can be start with calling run method.
 // Runners can be passed to goroutines, so should be concurrent safe.
 type Runner interface {
@@ -27,3 +32,68 @@ func NewTask(do func(interface{}, chan<- interface{}), arg interface{}) (*Task,
 	r := make(chan interface{}, 2)
 	return &Task{do: do, arg: arg, r: r}, r
 }
+
+// NewTaskContext creates a new task that passes the context to its function, and also returns the response channel to wait on.
+// If the context is already done when the task runs, the function is not called and the context error
+// is sent to the response channel instead, so the task invoker waiting on it is not blocked.
+func NewTaskContext(ctx context.Context, do func(context.Context, interface{}, chan<- interface{}), arg interface{}) (*Task, <-chan interface{}) {
+	return NewTask(func(arg interface{}, r chan<- interface{}) {
+		if e := ctx.Err(); e != nil {
+			r <- e
+			return
+		}
+
+		do(ctx, arg, r)
+	}, arg)
+}
+
+// Result is the outcome of a function execution, either a value or an error.
+type Result struct {
+	Value interface{}
+	Err   error
+}
+
+// NewResultTask creates a new task for a function returning a value or an error,
+// and also returns the result channel to wait on. The channel receives exactly one result.
+func NewResultTask(do func(interface{}) (interface{}, error), arg interface{}) (*Task, <-chan Result) {
+	// To protect the task invoker's goroutine from blocking.
+	r := make(chan Result, 1)
+	return &Task{do: func(arg interface{}, _ chan<- interface{}) {
+		v, e := do(arg)
+		r <- Result{Value: v, Err: e}
+	}, arg: arg}, r
+}
+
+// timeoutRunner is a runner that waits for the wrapped runner at most for a duration.
+type timeoutRunner struct {
+	runner    Runner
+	d         time.Duration
+	onTimeout func()
+}
+
+// WithTimeout wraps the runner in a runner that runs it on a new goroutine and waits for it at most for d.
+// If the runner does not complete in time, onTimeout is called if not nil and the waiting is abandoned.
+// Note the abandoned runner is not stopped, so it may still run to completion in the background.
+func WithTimeout(r Runner, d time.Duration, onTimeout func()) Runner {
+	return &timeoutRunner{runner: r, d: d, onTimeout: onTimeout}
+}
+
+// Run runs the wrapped runner and waits for it to complete or time out.
+func (r *timeoutRunner) Run() {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		r.runner.Run()
+	}()
+
+	timer := time.NewTimer(r.d)
+	defer timer.Stop()
+
+	select {
+	case <-done:
+	case <-timer.C:
+		if r.onTimeout != nil {
+			r.onTimeout()
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package concurrent
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestNewTask(t *testing.T) {
+	task, r := NewTask(func(arg interface{}, r chan<- interface{}) {
+		r <- arg.(int) * 2
+	}, 21)
+	task.Run()
+
+	if v := <-r; v != 42 {
+		t.Errorf("result = %v, want 42", v)
+	}
+}
+
+func TestNewTaskContext(t *testing.T) {
+	task, r := NewTaskContext(context.Background(), func(ctx context.Context, arg interface{}, r chan<- interface{}) {
+		r <- arg
+	}, "done")
+	task.Run()
+
+	if v := <-r; v != "done" {
+		t.Errorf("result = %v, want done", v)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	called := false
+	task, r = NewTaskContext(ctx, func(context.Context, interface{}, chan<- interface{}) {
+		called = true
+	}, nil)
+	task.Run()
+
+	if called {
+		t.Error("function is called with a done context")
+	}
+
+	if v := <-r; v != context.Canceled {
+		t.Errorf("result = %v, want context.Canceled", v)
+	}
+}
+
+func TestNewResultTask(t *testing.T) {
+	failure := errors.New("failure")
+	do := func(arg interface{}) (interface{}, error) {
+		if arg == nil {
+			return nil, failure
+		}
+
+		return arg.(int) * 2, nil
+	}
+
+	task, r := NewResultTask(do, nil)
+	task.Run()
+
+	if result := <-r; result.Err != failure || result.Value != nil {
+		t.Errorf("result = %+v, want the error", result)
+	}
+
+	task, r = NewResultTask(do, 21)
+	task.Run()
+
+	if result := <-r; result.Err != nil || result.Value != 42 {
+		t.Errorf("result = %+v, want 42", result)
+	}
+}
+
+// runnerFunc is a function implementing Runner.
+type runnerFunc func()
+
+func (f runnerFunc) Run() {
+	f()
+}
+
+func TestWithTimeout(t *testing.T) {
+	var timeouts int32
+	onTimeout := func() { atomic.AddInt32(&timeouts, 1) }
+
+	WithTimeout(runnerFunc(func() {}), time.Second, onTimeout).Run()
+	if n := atomic.LoadInt32(&timeouts); n != 0 {
+		t.Errorf("timeouts = %d after a fast runner, want 0", n)
+	}
+
+	release := make(chan struct{})
+	defer close(release)
+
+	start := time.Now()
+	WithTimeout(runnerFunc(func() { <-release }), 20*time.Millisecond, onTimeout).Run()
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Run of a slow runner took %v, want about the timeout", elapsed)
+	}
+
+	if n := atomic.LoadInt32(&timeouts); n != 1 {
+		t.Errorf("timeouts = %d after a slow runner, want 1", n)
+	}
+
+	WithTimeout(runnerFunc(func() { <-release }), time.Millisecond, nil).Run()
+}
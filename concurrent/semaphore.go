@@ -0,0 +1,117 @@
+package concurrent
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+)
+
+// Semaphore is a weighted semaphore limiting the concurrent access to a resource.
+// Waiting goroutines acquire the semaphore in the order they called Acquire.
+type Semaphore struct {
+	mutex   *sync.Mutex
+	size    int64
+	current int64
+	waiters *list.List
+}
+
+// waiter is a goroutine waiting to acquire n weights of semaphore, notified through the ready channel.
+type waiter struct {
+	n     int64
+	ready chan struct{}
+}
+
+// NewSemaphore creates a new semaphore with the maximum combined weight of size.
+func NewSemaphore(size int64) *Semaphore {
+	return &Semaphore{mutex: &sync.Mutex{}, size: size, waiters: list.New()}
+}
+
+// Acquire acquires the semaphore with the weight of n, blocking until the weight is available or the context is done.
+// On success it returns nil, otherwise the error of context and the semaphore is left unchanged.
+// It returns an error if n is larger than the size of semaphore, because it can never be acquired.
+func (s *Semaphore) Acquire(ctx context.Context, n int64) error {
+	s.mutex.Lock()
+	if n > s.size {
+		s.mutex.Unlock()
+		return errors.New("concurrent: semaphore weight exceeds its size")
+	}
+
+	if s.size-s.current >= n && s.waiters.Len() == 0 {
+		s.current += n
+		s.mutex.Unlock()
+		return nil
+	}
+
+	ready := make(chan struct{})
+	element := s.waiters.PushBack(waiter{n: n, ready: ready})
+	s.mutex.Unlock()
+
+	select {
+	case <-ready:
+		return nil
+	case <-ctx.Done():
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+
+		select {
+		case <-ready:
+			// Acquired right after the context is done, so keep it.
+			return nil
+		default:
+			front := s.waiters.Front() == element
+			s.waiters.Remove(element)
+			if front {
+				s.notify()
+			}
+
+			return ctx.Err()
+		}
+	}
+}
+
+// TryAcquire acquires the semaphore with the weight of n without blocking, and reports whether it succeeded.
+func (s *Semaphore) TryAcquire(n int64) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.size-s.current >= n && s.waiters.Len() == 0 {
+		s.current += n
+		return true
+	}
+
+	return false
+}
+
+// Release releases the semaphore with the weight of n.
+// It panics if the weight released is more than the weight held.
+func (s *Semaphore) Release(n int64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if n > s.current {
+		panic("concurrent: semaphore released more than held")
+	}
+
+	s.current -= n
+	s.notify()
+}
+
+// notify notifies the waiters in order, as long as the weight they wait for is available.
+func (s *Semaphore) notify() {
+	for {
+		front := s.waiters.Front()
+		if front == nil {
+			return
+		}
+
+		w := front.Value.(waiter)
+		if s.size-s.current < w.n {
+			return
+		}
+
+		s.current += w.n
+		s.waiters.Remove(front)
+		close(w.ready)
+	}
+}
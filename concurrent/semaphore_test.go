@@ -0,0 +1,90 @@
+package concurrent
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSemaphoreLimitsConcurrency(t *testing.T) {
+	s := NewSemaphore(3)
+
+	var current, max int32
+	wg := &sync.WaitGroup{}
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if e := s.Acquire(context.Background(), 1); e != nil {
+				t.Error(e)
+				return
+			}
+			defer s.Release(1)
+
+			n := atomic.AddInt32(&current, 1)
+			for {
+				m := atomic.LoadInt32(&max)
+				if n <= m || atomic.CompareAndSwapInt32(&max, m, n) {
+					break
+				}
+			}
+
+			time.Sleep(time.Millisecond)
+			atomic.AddInt32(&current, -1)
+		}()
+	}
+
+	wg.Wait()
+	if max > 3 {
+		t.Errorf("maximum concurrency = %d, want at most 3", max)
+	}
+}
+
+func TestSemaphoreWeights(t *testing.T) {
+	s := NewSemaphore(5)
+
+	if !s.TryAcquire(3) {
+		t.Fatal("TryAcquire(3) failed")
+	}
+
+	if s.TryAcquire(3) {
+		t.Fatal("TryAcquire(3) succeeded with 2 available")
+	}
+
+	if e := s.Acquire(context.Background(), 6); e == nil {
+		t.Error("Acquire of more than size succeeded, want an error")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if e := s.Acquire(ctx, 3); e != context.DeadlineExceeded {
+		t.Errorf("Acquire = %v, want context.DeadlineExceeded", e)
+	}
+
+	acquired := make(chan struct{})
+	go func() {
+		if e := s.Acquire(context.Background(), 5); e == nil {
+			close(acquired)
+		}
+	}()
+
+	s.Release(3)
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("waiter is not notified after Release")
+	}
+}
+
+func TestSemaphoreOverRelease(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("releasing more than held does not panic")
+		}
+	}()
+
+	NewSemaphore(1).Release(1)
+}
@@ -12,80 +12,488 @@
 // 2) If the instance is used to load a JSON configuration file, tries to load a node from JSON.
 //
 // Accessor methods can be used to convert loaded node or value to an appropriate type.
+//
+// String values may reference environment variables or other keys, like "https://${HOST}:${server.port}",
+// if interpolation is enabled through the EnableInterpolation method.
 package configuring
 
 import (
+	"bytes"
+	"context"
+	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"math"
+	"net"
+	"net/url"
 	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
 // ErrNotFoundOrNullValue determines a provided key not found, or the value is null.
+// The accessor methods return it wrapped in a KeyError carrying the requested key.
 var ErrNotFoundOrNullValue = errors.New("configuring: key not found or null value")
 
+// KeyError determines the key not found, or the value is null. It wraps ErrNotFoundOrNullValue.
+type KeyError struct {
+	Key string
+}
+
+// Error returns the error message containing the key.
+func (e *KeyError) Error() string {
+	if e.Key == "" {
+		return ErrNotFoundOrNullValue.Error()
+	}
+
+	return fmt.Sprintf("configuring: key %s not found or null value", e.Key)
+}
+
+// Unwrap returns ErrNotFoundOrNullValue, so errors.Is can be used to check the error.
+func (e *KeyError) Unwrap() error {
+	return ErrNotFoundOrNullValue
+}
+
+// Sources of the nodes, reported by the Source method.
+const (
+	// SourceEnv determines the node is loaded from an environment variable.
+	SourceEnv = "env"
+
+	// SourceFile determines the node is loaded from the loaded configuration, like a JSON file.
+	SourceFile = "file"
+
+	// SourceDefault determines the node is not found, so the default values of OrElse accessors are used.
+	SourceDefault = "default"
+)
+
+// watchInterval is the interval of checking loaded files for changes.
+const watchInterval = 500 * time.Millisecond
+
+// maxInterpolationDepth is the maximum depth of chained references expanded during interpolation.
+const maxInterpolationDepth = 10
+
+// errTrailingData determines there is some data after the top-level JSON value.
+var errTrailingData = errors.New("invalid data after top-level value")
+
 // Config encapsulates the configuration loading mechanism.
+// It is safe to get values while the configuration is being loaded from other goroutines, if created by New.
+// The zero value is an empty instance ready to use like the one created by New, but it is not safe for concurrent use.
 type Config struct {
-	content map[string]interface{}
-	node    interface{}
+	mutex       *sync.RWMutex
+	content     map[string]interface{}
+	node        interface{}
+	key         string
+	source      string
+	root        *Config
+	filenames   []string
+	loads       []func(*Config) error
+	overrides   map[string]override
+	sets        int
+	prefix      string
+	insensitive bool
+	interpolate bool
+	strict      bool
+}
+
+// override is a value set by Set, replayed by reload after the number of loads made before it.
+type override struct {
+	key      string
+	value    interface{}
+	loads    int
+	sequence int
 }
 
 // New creates a new configuration loading instance ready to load configuration values from.
 // The created instance can be used only to load environment variables.
 func New() *Config {
-	return &Config{content: make(map[string]interface{})}
+	c := &Config{mutex: &sync.RWMutex{}, content: make(map[string]interface{})}
+	c.root = c
+	return c
+}
+
+// WithEnvPrefix sets the prefix of environment variables and returns the instance itself.
+// For example, using the MYAPP prefix, the db.user key is looked up as MYAPP_DB_USER environment variable.
+// The prefix applies only to environment variables, not JSON keys. An empty prefix means no prefix.
+// Only instances returned by Get after calling this method are affected.
+func (c *Config) WithEnvPrefix(prefix string) *Config {
+	c.prefix = strings.TrimRight(prefix, "._")
+	return c
+}
+
+// CaseInsensitive makes the key lookups of loaded configuration case insensitive and returns the instance itself,
+// so Get("DB.User") finds the db.user key. Environment variable names are upper case regardless of this mode.
+// If more than one key of an object matches, the exactly matching key is preferred, otherwise one of them is used.
+// Set and LoadSecretsDir write to the existing keys matching the same way, instead of adding differently cased keys.
+// Only instances returned by Get after calling this method are affected.
+func (c *Config) CaseInsensitive() *Config {
+	c.insensitive = true
+	return c
+}
+
+// EnableInterpolation enables expanding ${KEY} references of string values and returns the instance itself.
+// A reference is resolved using KEY environment variable first, then the KEY node of loaded configuration.
+// Resolved values are expanded too, so references can be chained. $${KEY} is an escaped literal ${KEY}.
+// If strict is false, unresolved references are kept as is, otherwise the String accessor returns an error.
+// Only instances returned by Get after calling this method are affected.
+func (c *Config) EnableInterpolation(strict bool) *Config {
+	c.interpolate, c.strict = true, strict
+	return c
 }
 
 // LoadJSON loads JSON configuration file to the current instance and returns the instance itself.
 // The returned instance can be used to load environment variables and loaded JSON configuration file.
+// Decoding errors report the filename and, where possible, the line and column of the malformed content.
 func (c *Config) LoadJSON(filename string) (*Config, error) {
-	file, e := ioutil.ReadFile(filename)
+	file, e := os.Open(filename)
+	if e != nil {
+		return nil, e
+	}
+	defer file.Close()
+
+	content, e := decode(filename, file)
+	if e != nil {
+		return nil, e
+	}
+
+	c.merge(content, filename)
+	c.record(func(r *Config) error {
+		_, e := r.LoadJSON(filename)
+		return e
+	})
+
+	return c, nil
+}
+
+// LoadJSONReader loads JSON configuration from the reader to the current instance and returns the instance itself.
+// It can be used to load configuration from any source, like an embedded file or the body of an HTTP response.
+// Unlike the files loaded by LoadJSON, the loaded configuration is not watched by Watch.
+func (c *Config) LoadJSONReader(r io.Reader) (*Config, error) {
+	data, e := ioutil.ReadAll(r)
+	if e != nil {
+		return nil, e
+	}
+
+	content, e := decode("", bytes.NewReader(data))
 	if e != nil {
 		return nil, e
 	}
 
-	if e := json.Unmarshal(file, &c.content); e != nil {
+	c.merge(content, "")
+	c.record(func(r *Config) error {
+		_, e := r.LoadJSONBytes(data)
+		return e
+	})
+
+	return c, nil
+}
+
+// LoadJSONBytes loads JSON configuration from the data to the current instance and returns the instance itself.
+// It behaves like LoadJSON after reading the file, so it can be used when the configuration is already in memory.
+func (c *Config) LoadJSONBytes(data []byte) (*Config, error) {
+	return c.LoadJSONReader(bytes.NewReader(data))
+}
+
+// LoadSecretsDir loads secret files of the directory, like Docker or Kubernetes secrets mounted in /run/secrets,
+// to the current instance and returns the instance itself. The name of each file is used as the key,
+// so a file named db.password sets the db.password key, and its trimmed content is used as the value.
+// Hidden files and directories are skipped. The secrets override the values of same keys loaded before,
+// but environment variables still take precedence over them.
+func (c *Config) LoadSecretsDir(dir string) (*Config, error) {
+	entries, e := ioutil.ReadDir(dir)
+	if e != nil {
 		return nil, e
 	}
 
+	secrets := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+
+		filename := filepath.Join(dir, entry.Name())
+		if info, e := os.Stat(filename); e != nil || info.IsDir() {
+			continue
+		}
+
+		data, e := ioutil.ReadFile(filename)
+		if e != nil {
+			return nil, e
+		}
+
+		secrets[entry.Name()] = strings.TrimSpace(string(data))
+	}
+
+	c.lock()
+	for key, value := range secrets {
+		c.set(key, value)
+	}
+	c.unlock()
+
+	c.record(func(r *Config) error {
+		_, e := r.LoadSecretsDir(dir)
+		return e
+	})
+
 	return c, nil
 }
 
+// Watch watches the loaded JSON configuration files and calls onChange with a freshly reloaded instance,
+// whenever the files are changed. Rapid successive writes are coalesced into a single reload.
+// The reloaded instance replays everything loaded to the current instance in the same order, so the values
+// loaded by LoadJSONReader, LoadJSONBytes and Set are kept, and the secrets directories are read again.
+// Only the last value set by Set for each key is replayed, and the loads made after calling Watch are replayed too.
+// Only the files loaded by LoadJSON are watched for changes though.
+// If reloading fails, for example because a file is malformed, onChange is not called until the next change.
+// It blocks until the context is done, and returns an error if no file is loaded.
+func (c *Config) Watch(ctx context.Context, onChange func(*Config)) error {
+	if len(c.watched()) == 0 {
+		return errors.New("configuring: no file loaded to watch")
+	}
+
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+
+	last, changed := modifications(c.watched()), false
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if current := modifications(c.watched()); current != last {
+				last, changed = current, true
+				continue
+			}
+
+			if changed {
+				changed = false
+				if reloaded, e := c.reload(); e == nil {
+					onChange(reloaded)
+				}
+			}
+		}
+	}
+}
+
 // Get returns back a config instance that may be filled with an appropriate node instance.
 // The accessor methods can be used to convert the node to a specific type.
+// If the key is not found, the returned instance contains no node, so the OrElse accessors return the default value.
 func (c *Config) Get(key string) *Config {
-	if v, exists := os.LookupEnv(asEnv(key)); exists {
-		return &Config{content: c.content, node: v}
+	requested := key
+	if c.key != "" {
+		requested = c.key + "." + key
+	}
+
+	if v, exists := os.LookupEnv(c.env(key)); exists {
+		found := c.derive(requested, c.content, v)
+		found.source = SourceEnv
+		return found
 	}
 
+	c.rlock()
+	defer c.runlock()
+
 	temp := c
 	for _, part := range split(key) {
-		if v, exists := temp.content[part]; exists {
+		if v, exists := c.lookup(temp.content, part); exists {
 			if m, ok := v.(map[string]interface{}); ok {
-				temp = &Config{content: m, node: v}
+				temp = c.derive(requested, m, v)
 			} else {
-				temp = &Config{content: make(map[string]interface{}), node: v}
+				temp = c.derive(requested, make(map[string]interface{}), v)
 			}
 		} else {
-			return c
+			return c.derive(requested, make(map[string]interface{}), nil)
 		}
 	}
 
+	temp.source = SourceFile
 	return temp
 }
 
+// GetAny returns back a config instance filled with the node of the first key found, trying keys in order.
+// It can be used when a value may be defined using different keys, for example GetAny("server.port", "port").
+// If none of the keys is found, the returned instance contains no node, like Get.
+func (c *Config) GetAny(keys ...string) *Config {
+	for _, key := range keys {
+		if found := c.Get(key); found.node != nil {
+			return found
+		}
+	}
+
+	if len(keys) == 0 {
+		return c.derive(c.key, make(map[string]interface{}), nil)
+	}
+
+	return c.Get(keys[0])
+}
+
+// GetPointer returns back a config instance filled with the node addressed by an RFC 6901 JSON Pointer,
+// like "/servers/0/host", so elements of arrays are reachable unlike Get. The pointer is resolved relative to
+// the node of instance, "~1" and "~0" are unescaped to "/" and "~", and environment variables are not looked up.
+// If the pointer is invalid or not found, including an array index out of range, the returned instance contains no node.
+func (c *Config) GetPointer(ptr string) *Config {
+	c.rlock()
+	defer c.runlock()
+
+	var node interface{} = c.content
+	if c.node != nil {
+		node = c.node
+	}
+
+	if ptr != "" && !strings.HasPrefix(ptr, "/") {
+		return c.derive(c.key, make(map[string]interface{}), nil)
+	}
+
+	parts := make([]string, 0)
+	if ptr != "" {
+		for _, token := range strings.Split(ptr[1:], "/") {
+			parts = append(parts, strings.Replace(strings.Replace(token, "~1", "/", -1), "~0", "~", -1))
+		}
+	}
+
+	requested := c.key
+	if c.key != "" && len(parts) > 0 {
+		requested += "."
+	}
+	requested += strings.Join(parts, ".")
+
+	for _, part := range parts {
+		switch v := node.(type) {
+		case map[string]interface{}:
+			found, exists := c.lookup(v, part)
+			if !exists {
+				return c.derive(requested, make(map[string]interface{}), nil)
+			}
+			node = found
+		case []interface{}:
+			i, e := strconv.Atoi(part)
+			if e != nil || part[0] < '0' || part[0] > '9' || (len(part) > 1 && part[0] == '0') || i >= len(v) {
+				return c.derive(requested, make(map[string]interface{}), nil)
+			}
+			node = v[i]
+		default:
+			return c.derive(requested, make(map[string]interface{}), nil)
+		}
+	}
+
+	found := c.derive(requested, make(map[string]interface{}), node)
+	if m, ok := node.(map[string]interface{}); ok {
+		found.content = m
+	}
+
+	found.source = SourceFile
+	return found
+}
+
+// Sub returns a new instance containing a copy of the object node of the key, so its nested keys can be loaded
+// using relative keys, and setting its values does not affect the current instance.
+// Environment variables are looked up by the relative keys too, with the prefix set by WithEnvPrefix if any,
+// for example Sub("db").Get("user") looks up USER, or MYAPP_USER using the MYAPP prefix.
+// If the key is not an object node, an empty instance is returned.
+func (c *Config) Sub(key string) *Config {
+	sub := c.fresh()
+	if m, ok := c.Get(key).node.(map[string]interface{}); ok {
+		c.rlock()
+		sub.content = clone(m).(map[string]interface{})
+		c.runlock()
+	}
+
+	return sub
+}
+
+// Clone returns a deep copy of the instance, so setting values of the copy does not affect the original instance.
+func (c *Config) Clone() *Config {
+	c.rlock()
+	defer c.runlock()
+
+	content := clone(c.content).(map[string]interface{})
+	node := clone(c.node)
+	if _, ok := c.node.(map[string]interface{}); ok {
+		node = content
+	}
+
+	d := c.derive(c.key, content, node)
+	d.mutex = &sync.RWMutex{}
+	d.filenames, d.loads = append([]string(nil), c.filenames...), append([]func(*Config) error(nil), c.loads...)
+	d.overrides, d.sets = make(map[string]override, len(c.overrides)), c.sets
+	for key, o := range c.overrides {
+		d.overrides[key] = o
+	}
+	if c.root == c || c.root == nil {
+		d.root = d
+	}
+
+	return d
+}
+
+// Set sets the value of a key, creating the intermediate nodes if needed.
+// Environment variables still take precedence over the values set.
+//
+// The value is converted to the types of decoded JSON, so it can be read back by the accessors like a loaded value:
+// strings, booleans, numbers, slices, maps and structs are converted like encoding them to JSON and decoding back,
+// so for example Set("port", 8080) can be read by Uint16 and Set("hosts", []string{...}) by SliceOfString.
+// Durations, URLs and CIDRs are converted to their string representation, readable by Duration, URL and CIDR.
+// A value not encodable to JSON is set as is, so it can only be read by Unmarshal if at all.
+func (c *Config) Set(key string, value interface{}) {
+	value = normalize(value)
+
+	// Object nodes are modified in place, so the accessors reading object nodes of the instances returned by Get
+	// hold the read lock. Arrays are never modified in place, they are replaced as a whole.
+	c.lock()
+	defer c.unlock()
+
+	c.set(key, value)
+	c.override(key, value)
+}
+
+// Exists reports whether the node is resolved, either from environment variables or the loaded JSON.
+// A key defined with an empty value, for example an environment variable set to empty string, exists.
+func (c *Config) Exists() bool {
+	return c.node != nil
+}
+
+// Source reports where the node is loaded from: SourceEnv, SourceFile or SourceDefault if it is not found.
+// The values set by Set or LoadSecretsDir are part of the loaded configuration, so they are reported as SourceFile.
+func (c *Config) Source() string {
+	if c.node == nil {
+		return SourceDefault
+	}
+
+	return c.source
+}
+
+// Require checks all the keys are found with non-null values, and returns an error listing every missing key if any.
+func (c *Config) Require(keys ...string) error {
+	missing := make([]string, 0)
+	for _, key := range keys {
+		if !c.Get(key).Exists() {
+			missing = append(missing, key)
+		}
+	}
+
+	if len(missing) > 0 {
+		return errors.New(fmt.Sprintf("configuring: required keys not found or null value: %s", strings.Join(missing, ", ")))
+	}
+
+	return nil
+}
+
 // String returns the string representation of a node if convertible.
 func (c *Config) String() (string, error) {
 	if c.node == nil {
-		return "", ErrNotFoundOrNullValue
+		return "", c.notFound()
 	}
 
 	if v, ok := c.node.(string); ok {
-		return v, nil
+		return c.expand(v, 0)
 	}
 
 	return "", errors.New(fmt.Sprintf("configuring: %T to string not supported", c.node))
@@ -98,16 +506,59 @@ func (c *Config) StringOrElse(value string) string {
 	}
 
 	if v, ok := c.node.(string); ok {
-		return v
+		if s, e := c.expand(v, 0); e == nil {
+			return s
+		}
 	}
 
 	return value
 }
 
+// StringOneOf returns the string representation of a node if convertible and one of the allowed values.
+// The values are compared case sensitively.
+func (c *Config) StringOneOf(allowed ...string) (string, error) {
+	v, e := c.String()
+	if e != nil {
+		return "", e
+	}
+
+	for _, a := range allowed {
+		if v == a {
+			return v, nil
+		}
+	}
+
+	return "", errors.New(fmt.Sprintf("configuring: %q is not one of [%s]", v, strings.Join(allowed, ", ")))
+}
+
+// StringFirst returns the string representation of the first key holding a non-empty string, trying keys in order.
+// Unlike GetAny, which returns the first key found even if its value is an empty string, keys with empty values
+// are skipped like the keys not found, for example when a legacy key is defined but left blank.
+// A key found whose node is not convertible to string is an error. If no key holds a non-empty string,
+// the error wraps ErrNotFoundOrNullValue.
+func (c *Config) StringFirst(keys ...string) (string, error) {
+	for _, key := range keys {
+		v, e := c.Get(key).String()
+		if errors.Is(e, ErrNotFoundOrNullValue) {
+			continue
+		}
+
+		if e != nil {
+			return "", e
+		}
+
+		if v != "" {
+			return v, nil
+		}
+	}
+
+	return "", fmt.Errorf("configuring: no non-empty value for keys %s: %w", strings.Join(keys, ", "), ErrNotFoundOrNullValue)
+}
+
 // Bool returns the boolean representation of a node if convertible.
 func (c *Config) Bool() (bool, error) {
 	if c.node == nil {
-		return false, ErrNotFoundOrNullValue
+		return false, c.notFound()
 	}
 
 	if v, ok := c.node.(bool); ok {
@@ -139,41 +590,93 @@ func (c *Config) BoolOrElse(value bool) bool {
 }
 
 // Int returns the integer representation of a node if convertible.
+// A floating point node is convertible only if it has no fractional part and fits in int.
 func (c *Config) Int() (int, error) {
-	if c.node == nil {
-		return 0, ErrNotFoundOrNullValue
+	v, e := c.signed(strconv.IntSize, "int")
+	return int(v), e
+}
+
+// IntOrElse returns the integer representation of a node if convertible otherwise the default value provided.
+func (c *Config) IntOrElse(value int) int {
+	if v, e := c.Int(); e == nil {
+		return v
 	}
 
-	if v, ok := c.node.(int); ok {
-		return v, nil
+	return value
+}
+
+// IntInRange returns the integer representation of a node if convertible and in the [min, max] range.
+func (c *Config) IntInRange(min, max int) (int, error) {
+	v, e := c.Int()
+	if e != nil {
+		return 0, e
 	}
 
-	if v, ok := c.node.(float64); ok {
-		return int(v), nil
+	if v < min {
+		return 0, errors.New(fmt.Sprintf("configuring: %d is less than minimum %d", v, min))
 	}
 
-	if v, e := strconv.Atoi(c.StringOrElse("")); e == nil {
-		return v, nil
+	if v > max {
+		return 0, errors.New(fmt.Sprintf("configuring: %d is greater than maximum %d", v, max))
 	}
 
-	return 0, errors.New(fmt.Sprintf("configuring: %T to int not supported", c.node))
+	return v, nil
 }
 
-// IntOrElse returns the integer representation of a node if convertible otherwise the default value provided.
-func (c *Config) IntOrElse(value int) int {
-	if c.node == nil {
-		return value
+// Int64 returns the 64-bit integer representation of a node if convertible.
+// A floating point node is convertible only if it has no fractional part and fits in int64.
+func (c *Config) Int64() (int64, error) {
+	return c.signed(64, "int64")
+}
+
+// Int64OrElse returns the 64-bit integer representation of a node if convertible otherwise the default value provided.
+func (c *Config) Int64OrElse(value int64) int64 {
+	if v, e := c.Int64(); e == nil {
+		return v
 	}
 
-	if v, ok := c.node.(int); ok {
+	return value
+}
+
+// Int32 returns the 32-bit integer representation of a node if convertible.
+func (c *Config) Int32() (int32, error) {
+	v, e := c.signed(32, "int32")
+	return int32(v), e
+}
+
+// Int32OrElse returns the 32-bit integer representation of a node if convertible otherwise the default value provided.
+func (c *Config) Int32OrElse(value int32) int32 {
+	if v, e := c.Int32(); e == nil {
 		return v
 	}
 
-	if v, ok := c.node.(float64); ok {
-		return int(v)
+	return value
+}
+
+// Int16 returns the 16-bit integer representation of a node if convertible.
+func (c *Config) Int16() (int16, error) {
+	v, e := c.signed(16, "int16")
+	return int16(v), e
+}
+
+// Int16OrElse returns the 16-bit integer representation of a node if convertible otherwise the default value provided.
+func (c *Config) Int16OrElse(value int16) int16 {
+	if v, e := c.Int16(); e == nil {
+		return v
 	}
 
-	if v, e := strconv.Atoi(c.StringOrElse("")); e == nil {
+	return value
+}
+
+// Int8 returns the 8-bit integer representation of a node if convertible.
+func (c *Config) Int8() (int8, error) {
+	v, e := c.signed(8, "int8")
+	return int8(v), e
+}
+
+// Int8OrElse returns the 8-bit integer representation of a node if convertible otherwise the default value provided.
+func (c *Config) Int8OrElse(value int8) int8 {
+	if v, e := c.Int8(); e == nil {
 		return v
 	}
 
@@ -181,42 +684,76 @@ func (c *Config) IntOrElse(value int) int {
 }
 
 // Uint returns the unsigned integer representation of a node if convertible.
+// A floating point node is convertible only if it is not negative, has no fractional part and fits in uint.
 func (c *Config) Uint() (uint, error) {
-	if c.node == nil {
-		return 0, ErrNotFoundOrNullValue
-	}
+	v, e := c.unsigned(strconv.IntSize, "uint")
+	return uint(v), e
+}
 
-	if v, ok := c.node.(uint); ok {
-		return v, nil
+// UintOrElse returns the unsigned integer representation of a node if convertible otherwise the default value provided.
+func (c *Config) UintOrElse(value uint) uint {
+	if v, e := c.Uint(); e == nil {
+		return v
 	}
 
-	if v, ok := c.node.(float64); ok {
-		return uint(v), nil
-	}
+	return value
+}
 
-	if v, e := strconv.ParseUint(c.StringOrElse(""), 10, 0); e == nil {
-		return uint(v), nil
+// Uint64 returns the 64-bit unsigned integer representation of a node if convertible.
+// A floating point node is convertible only if it is not negative, has no fractional part and fits in uint64.
+func (c *Config) Uint64() (uint64, error) {
+	return c.unsigned(64, "uint64")
+}
+
+// Uint64OrElse returns the 64-bit unsigned integer representation of a node if convertible otherwise the default value provided.
+func (c *Config) Uint64OrElse(value uint64) uint64 {
+	if v, e := c.Uint64(); e == nil {
+		return v
 	}
 
-	return 0, errors.New(fmt.Sprintf("configuring: %T to uint not supported", c.node))
+	return value
 }
 
-// UintOrElse returns the unsigned integer representation of a node if convertible otherwise the default value provided.
-func (c *Config) UintOrElse(value uint) uint {
-	if c.node == nil {
-		return value
-	}
+// Uint32 returns the 32-bit unsigned integer representation of a node if convertible.
+func (c *Config) Uint32() (uint32, error) {
+	v, e := c.unsigned(32, "uint32")
+	return uint32(v), e
+}
 
-	if v, ok := c.node.(uint); ok {
+// Uint32OrElse returns the 32-bit unsigned integer representation of a node if convertible otherwise the default value provided.
+func (c *Config) Uint32OrElse(value uint32) uint32 {
+	if v, e := c.Uint32(); e == nil {
 		return v
 	}
 
-	if v, ok := c.node.(float64); ok {
-		return uint(v)
+	return value
+}
+
+// Uint16 returns the 16-bit unsigned integer representation of a node if convertible.
+func (c *Config) Uint16() (uint16, error) {
+	v, e := c.unsigned(16, "uint16")
+	return uint16(v), e
+}
+
+// Uint16OrElse returns the 16-bit unsigned integer representation of a node if convertible otherwise the default value provided.
+func (c *Config) Uint16OrElse(value uint16) uint16 {
+	if v, e := c.Uint16(); e == nil {
+		return v
 	}
 
-	if v, e := strconv.ParseUint(c.StringOrElse(""), 10, 0); e == nil {
-		return uint(v)
+	return value
+}
+
+// Uint8 returns the 8-bit unsigned integer representation of a node if convertible.
+func (c *Config) Uint8() (uint8, error) {
+	v, e := c.unsigned(8, "uint8")
+	return uint8(v), e
+}
+
+// Uint8OrElse returns the 8-bit unsigned integer representation of a node if convertible otherwise the default value provided.
+func (c *Config) Uint8OrElse(value uint8) uint8 {
+	if v, e := c.Uint8(); e == nil {
+		return v
 	}
 
 	return value
@@ -225,7 +762,7 @@ func (c *Config) UintOrElse(value uint) uint {
 // Float32 returns the floating point representation of a node if convertible.
 func (c *Config) Float32() (float32, error) {
 	if c.node == nil {
-		return 0, ErrNotFoundOrNullValue
+		return 0, c.notFound()
 	}
 
 	if v, ok := c.node.(float32); ok {
@@ -236,7 +773,13 @@ func (c *Config) Float32() (float32, error) {
 		return float32(v), nil
 	}
 
-	if v, e := strconv.ParseFloat(c.StringOrElse(""), 32); e == nil {
+	if v, ok := c.node.(json.Number); ok {
+		if f, e := parseFloat(v.String(), 32); e == nil {
+			return float32(f), nil
+		}
+	}
+
+	if v, e := parseFloat(c.StringOrElse(""), 32); e == nil {
 		return float32(v), nil
 	}
 
@@ -257,7 +800,13 @@ func (c *Config) Float32OrElse(value float32) float32 {
 		return float32(v)
 	}
 
-	if v, e := strconv.ParseFloat(c.StringOrElse(""), 32); e == nil {
+	if v, ok := c.node.(json.Number); ok {
+		if f, e := parseFloat(v.String(), 32); e == nil {
+			return float32(f)
+		}
+	}
+
+	if v, e := parseFloat(c.StringOrElse(""), 32); e == nil {
 		return float32(v)
 	}
 
@@ -267,14 +816,20 @@ func (c *Config) Float32OrElse(value float32) float32 {
 // Float64 returns the floating point representation of a node if convertible.
 func (c *Config) Float64() (float64, error) {
 	if c.node == nil {
-		return 0, ErrNotFoundOrNullValue
+		return 0, c.notFound()
 	}
 
 	if v, ok := c.node.(float64); ok {
 		return v, nil
 	}
 
-	if v, e := strconv.ParseFloat(c.StringOrElse(""), 64); e == nil {
+	if v, ok := c.node.(json.Number); ok {
+		if f, e := parseFloat(v.String(), 64); e == nil {
+			return f, nil
+		}
+	}
+
+	if v, e := parseFloat(c.StringOrElse(""), 64); e == nil {
 		return v, nil
 	}
 
@@ -291,15 +846,43 @@ func (c *Config) Float64OrElse(value float64) float64 {
 		return v
 	}
 
-	if v, e := strconv.ParseFloat(c.StringOrElse(""), 64); e == nil {
+	if v, ok := c.node.(json.Number); ok {
+		if f, e := parseFloat(v.String(), 64); e == nil {
+			return f
+		}
+	}
+
+	if v, e := parseFloat(c.StringOrElse(""), 64); e == nil {
 		return v
 	}
 
 	return value
 }
 
+// Float64InRange returns the floating point representation of a node if convertible and in the [min, max] range.
+func (c *Config) Float64InRange(min, max float64) (float64, error) {
+	v, e := c.Float64()
+	if e != nil {
+		return 0, e
+	}
+
+	if v < min {
+		return 0, errors.New(fmt.Sprintf("configuring: %g is less than minimum %g", v, min))
+	}
+
+	if v > max {
+		return 0, errors.New(fmt.Sprintf("configuring: %g is greater than maximum %g", v, max))
+	}
+
+	return v, nil
+}
+
 // Duration returns the duration representation of a node if convertible.
 func (c *Config) Duration() (time.Duration, error) {
+	if c.node == nil {
+		return 0, c.notFound()
+	}
+
 	d, e := time.ParseDuration(c.StringOrElse(""))
 	if e != nil {
 		return 0, errors.New(fmt.Sprintf("configuring: %T to duration not supported", c.node))
@@ -318,26 +901,104 @@ func (c *Config) DurationOrElse(value time.Duration) time.Duration {
 	return d
 }
 
-// SliceOfString returns the slice of string representation of a node if convertible.
-func (c *Config) SliceOfString() ([]string, error) {
-	if c.node == nil {
-		return nil, ErrNotFoundOrNullValue
+// IP returns the IP address representation of a node if convertible.
+func (c *Config) IP() (net.IP, error) {
+	v, e := c.String()
+	if e != nil {
+		return nil, e
 	}
 
-	if vs, ok := c.node.([]interface{}); ok {
-		ss := make([]string, 0)
-		for _, v := range vs {
-			if s, ok := v.(string); ok {
-				ss = append(ss, s)
-			} else {
-				return nil, errors.New(fmt.Sprintf("configuring: %T to string not supported", v))
-			}
-		}
-
-		return ss, nil
+	ip := net.ParseIP(v)
+	if ip == nil {
+		return nil, errors.New(fmt.Sprintf("configuring: %q is not a valid IP address", v))
 	}
 
-	return nil, errors.New(fmt.Sprintf("configuring: %T to []string not supported", c.node))
+	return ip, nil
+}
+
+// IPOrElse returns the IP address representation of a node if convertible otherwise the default value provided.
+func (c *Config) IPOrElse(value net.IP) net.IP {
+	if ip, e := c.IP(); e == nil {
+		return ip
+	}
+
+	return value
+}
+
+// CIDR returns the IP network representation of a node in CIDR notation, like 192.0.2.0/24, if convertible.
+func (c *Config) CIDR() (*net.IPNet, error) {
+	v, e := c.String()
+	if e != nil {
+		return nil, e
+	}
+
+	_, network, e := net.ParseCIDR(v)
+	if e != nil {
+		return nil, errors.New(fmt.Sprintf("configuring: %q is not a valid CIDR notation", v))
+	}
+
+	return network, nil
+}
+
+// CIDROrElse returns the IP network representation of a node in CIDR notation if convertible
+// otherwise the default value provided.
+func (c *Config) CIDROrElse(value *net.IPNet) *net.IPNet {
+	if network, e := c.CIDR(); e == nil {
+		return network
+	}
+
+	return value
+}
+
+// URL returns the URL representation of a node if convertible.
+// The URL should be absolute, containing both scheme and host, so a string like example.com/path is not convertible.
+func (c *Config) URL() (*url.URL, error) {
+	v, e := c.String()
+	if e != nil {
+		return nil, e
+	}
+
+	u, e := url.Parse(v)
+	if e != nil {
+		return nil, errors.New(fmt.Sprintf("configuring: %q is not a valid URL", v))
+	}
+
+	if u.Scheme == "" || u.Host == "" {
+		return nil, errors.New(fmt.Sprintf("configuring: %q is not an absolute URL with scheme and host", v))
+	}
+
+	return u, nil
+}
+
+// URLOrElse returns the URL representation of a node if convertible otherwise the default value provided.
+func (c *Config) URLOrElse(value *url.URL) *url.URL {
+	if u, e := c.URL(); e == nil {
+		return u
+	}
+
+	return value
+}
+
+// SliceOfString returns the slice of string representation of a node if convertible.
+func (c *Config) SliceOfString() ([]string, error) {
+	if c.node == nil {
+		return nil, c.notFound()
+	}
+
+	if vs, ok := c.node.([]interface{}); ok {
+		ss := make([]string, 0)
+		for _, v := range vs {
+			if s, ok := v.(string); ok {
+				ss = append(ss, s)
+			} else {
+				return nil, errors.New(fmt.Sprintf("configuring: %T to string not supported", v))
+			}
+		}
+
+		return ss, nil
+	}
+
+	return nil, errors.New(fmt.Sprintf("configuring: %T to []string not supported", c.node))
 }
 
 // SliceOfStringOrElse returns the slice of string representation of a node if convertible, otherwise the default value provided.
@@ -362,6 +1023,643 @@ func (c *Config) SliceOfStringOrElse(value []string) []string {
 	return ss
 }
 
+// SliceOfInt returns the slice of integer representation of a node if convertible.
+// Each element is converted like Int, so an element with fractional part or not fitting in int is an error
+// reporting the index of element.
+func (c *Config) SliceOfInt() ([]int, error) {
+	if c.node == nil {
+		return nil, c.notFound()
+	}
+
+	if vs, ok := c.node.([]interface{}); ok {
+		is := make([]int, 0)
+		for i, v := range vs {
+			n, e := c.derive(fmt.Sprintf("%s.%d", c.key, i), make(map[string]interface{}), v).signed(strconv.IntSize, "int")
+			if e != nil {
+				return nil, errors.New(fmt.Sprintf("configuring: element %d: %s", i, strings.TrimPrefix(e.Error(), "configuring: ")))
+			}
+
+			is = append(is, int(n))
+		}
+
+		return is, nil
+	}
+
+	return nil, errors.New(fmt.Sprintf("configuring: %T to []int not supported", c.node))
+}
+
+// SliceOfIntOrElse returns the slice of integer representation of a node if convertible, otherwise the default value provided.
+func (c *Config) SliceOfIntOrElse(value []int) []int {
+	if is, e := c.SliceOfInt(); e == nil {
+		return is
+	}
+
+	return value
+}
+
+// SliceOfFloat64 returns the slice of floating point representation of a node if convertible.
+func (c *Config) SliceOfFloat64() ([]float64, error) {
+	if c.node == nil {
+		return nil, c.notFound()
+	}
+
+	if vs, ok := c.node.([]interface{}); ok {
+		fs := make([]float64, 0)
+		for i, v := range vs {
+			switch n := v.(type) {
+			case float64:
+				fs = append(fs, n)
+			case json.Number:
+				f, e := parseFloat(n.String(), 64)
+				if e != nil {
+					return nil, errors.New(fmt.Sprintf("configuring: element %d: %s overflows float64", i, n))
+				}
+
+				fs = append(fs, f)
+			default:
+				return nil, errors.New(fmt.Sprintf("configuring: element %d: %T to float64 not supported", i, v))
+			}
+		}
+
+		return fs, nil
+	}
+
+	return nil, errors.New(fmt.Sprintf("configuring: %T to []float64 not supported", c.node))
+}
+
+// SliceOfFloat64OrElse returns the slice of floating point representation of a node if convertible, otherwise the default value provided.
+func (c *Config) SliceOfFloat64OrElse(value []float64) []float64 {
+	if fs, e := c.SliceOfFloat64(); e == nil {
+		return fs
+	}
+
+	return value
+}
+
+// Slice returns a config instance for each element of an array node, so the elements like objects
+// can be accessed using their own Get, for example to iterate over the servers of "servers": [{"host": ...}, ...].
+func (c *Config) Slice() ([]*Config, error) {
+	if c.node == nil {
+		return nil, c.notFound()
+	}
+
+	vs, ok := c.node.([]interface{})
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("configuring: %T to []*Config not supported", c.node))
+	}
+
+	cs := make([]*Config, 0, len(vs))
+	for i, v := range vs {
+		content, _ := v.(map[string]interface{})
+		if content == nil {
+			content = make(map[string]interface{})
+		}
+
+		cs = append(cs, c.derive(fmt.Sprintf("%s.%d", c.key, i), content, v))
+	}
+
+	return cs, nil
+}
+
+// MapOfString returns the map of string representation of a node if convertible.
+func (c *Config) MapOfString() (map[string]string, error) {
+	if c.node == nil {
+		return nil, c.notFound()
+	}
+
+	c.rlock()
+	defer c.runlock()
+
+	if vs, ok := c.node.(map[string]interface{}); ok {
+		ss := make(map[string]string, len(vs))
+		for k, v := range vs {
+			if s, ok := v.(string); ok {
+				ss[k] = s
+			} else {
+				return nil, errors.New(fmt.Sprintf("configuring: %s: %T to string not supported", k, v))
+			}
+		}
+
+		return ss, nil
+	}
+
+	return nil, errors.New(fmt.Sprintf("configuring: %T to map[string]string not supported", c.node))
+}
+
+// MapOfStringOrElse returns the map of string representation of a node if convertible, otherwise the default value provided.
+func (c *Config) MapOfStringOrElse(value map[string]string) map[string]string {
+	if ss, e := c.MapOfString(); e == nil {
+		return ss
+	}
+
+	return value
+}
+
+// MapOfStringFromEnv returns the map of string representation of a node in the form of "k1=v1,k2=v2",
+// which is the common way of passing a small map through a single environment variable.
+// Pairs are split on commas and then on the first '=', and spaces around keys and values are trimmed.
+// An empty string is an empty map, and a pair without '=' or with an empty key is an error.
+// A JSON object node is converted like MapOfString, so the map can be provided by a file too.
+func (c *Config) MapOfStringFromEnv() (map[string]string, error) {
+	if _, ok := c.node.(map[string]interface{}); ok {
+		return c.MapOfString()
+	}
+
+	v, e := c.String()
+	if e != nil {
+		return nil, e
+	}
+
+	ss := make(map[string]string)
+	if strings.TrimSpace(v) == "" {
+		return ss, nil
+	}
+
+	for _, pair := range strings.Split(v, ",") {
+		i := strings.Index(pair, "=")
+		if i < 0 || strings.TrimSpace(pair[:i]) == "" {
+			return nil, errors.New(fmt.Sprintf("configuring: malformed pair %q, key=value expected", pair))
+		}
+
+		ss[strings.TrimSpace(pair[:i])] = strings.TrimSpace(pair[i+1:])
+	}
+
+	return ss, nil
+}
+
+// ToJSON returns the effective configuration as indented JSON, which is the loaded configuration
+// with the values overridden by environment variables replaced.
+func (c *Config) ToJSON() ([]byte, error) {
+	c.rlock()
+	content := c.effective(c.content, "")
+	c.runlock()
+
+	return json.MarshalIndent(content, "", "  ")
+}
+
+// ToJSONRedacted is like ToJSON, but replaces the values of sensitive keys with "***", so the dump is safe to share.
+// Sensitive keys are dotted keys or patterns like *.password, matched using path.Match where * matches dots too.
+// The elements of arrays are matched by their index, so *.password redacts servers.0.password too.
+// If a matched key is an object or array node, the whole node is redacted.
+func (c *Config) ToJSONRedacted(sensitive ...string) ([]byte, error) {
+	c.rlock()
+	content := c.effective(c.content, "")
+	c.runlock()
+
+	if _, e := redact(content, "", sensitive); e != nil {
+		return nil, e
+	}
+
+	return json.MarshalIndent(content, "", "  ")
+}
+
+// CertKeyPair loads the TLS certificate using the files defined by tls.cert_file and tls.key_file keys.
+func (c *Config) CertKeyPair() (tls.Certificate, error) {
+	certFile, e := c.Get("tls.cert_file").String()
+	if e != nil {
+		return tls.Certificate{}, fmt.Errorf("configuring: tls.cert_file: %w", e)
+	}
+
+	keyFile, e := c.Get("tls.key_file").String()
+	if e != nil {
+		return tls.Certificate{}, fmt.Errorf("configuring: tls.key_file: %w", e)
+	}
+
+	certificate, e := tls.LoadX509KeyPair(certFile, keyFile)
+	if e != nil {
+		return tls.Certificate{}, fmt.Errorf("configuring: loading %s and %s: %w", certFile, keyFile, e)
+	}
+
+	return certificate, nil
+}
+
+// Unmarshal binds the node to the target, which should be a pointer, respecting its json tags.
+// For example Get("db").Unmarshal(&db) fills the db struct using the db node.
+func (c *Config) Unmarshal(target interface{}) error {
+	if c.node == nil {
+		return c.notFound()
+	}
+
+	if v := reflect.ValueOf(target); v.Kind() != reflect.Ptr || v.IsNil() {
+		return errors.New(fmt.Sprintf("configuring: unmarshal to non-pointer %T not supported", target))
+	}
+
+	c.rlock()
+	data, e := json.Marshal(c.node)
+	c.runlock()
+	if e != nil {
+		return e
+	}
+
+	return json.Unmarshal(data, target)
+}
+
+// derive creates a new instance sharing the settings of the current instance, filled with the key, content and node provided.
+// The loaded state, like the loaded files and the values set, is not copied, since it is written by the loading
+// methods under the lock, while derive is called without holding it.
+func (c *Config) derive(key string, content map[string]interface{}, node interface{}) *Config {
+	d := &Config{
+		mutex:       c.mutex,
+		content:     content,
+		node:        node,
+		key:         key,
+		source:      c.source,
+		root:        c.root,
+		prefix:      c.prefix,
+		insensitive: c.insensitive,
+		interpolate: c.interpolate,
+		strict:      c.strict,
+	}
+
+	if d.root == nil {
+		// The zero value instance is the root of itself.
+		d.root = c
+	}
+
+	return d
+}
+
+// lock locks the instance for writing. The zero value instance has no mutex, so it is not locked.
+func (c *Config) lock() {
+	if c.mutex != nil {
+		c.mutex.Lock()
+	}
+}
+
+// unlock unlocks the instance locked for writing.
+func (c *Config) unlock() {
+	if c.mutex != nil {
+		c.mutex.Unlock()
+	}
+}
+
+// rlock locks the instance for reading. The zero value instance has no mutex, so it is not locked.
+func (c *Config) rlock() {
+	if c.mutex != nil {
+		c.mutex.RLock()
+	}
+}
+
+// runlock unlocks the instance locked for reading.
+func (c *Config) runlock() {
+	if c.mutex != nil {
+		c.mutex.RUnlock()
+	}
+}
+
+// lookup looks up the value of a key part in the content, considering the case insensitive mode.
+func (c *Config) lookup(content map[string]interface{}, part string) (interface{}, bool) {
+	v, exists := content[c.resolve(content, part)]
+	return v, exists
+}
+
+// resolve returns the key of the content matching the key part, considering the case insensitive mode.
+// If no key matches, the key part itself is returned.
+func (c *Config) resolve(content map[string]interface{}, part string) string {
+	if _, exists := content[part]; exists || !c.insensitive {
+		return part
+	}
+
+	for k := range content {
+		if strings.EqualFold(k, part) {
+			return k
+		}
+	}
+
+	return part
+}
+
+// merge merges the loaded content to the content of current instance, and records the file it is loaded from if any.
+func (c *Config) merge(content map[string]interface{}, filename string) {
+	c.lock()
+	defer c.unlock()
+
+	if c.content == nil {
+		c.content = make(map[string]interface{}, len(content))
+	}
+
+	for k, v := range content {
+		c.content[k] = v
+	}
+
+	if filename != "" {
+		c.filenames = append(c.filenames, filename)
+	}
+}
+
+// set sets the value of a key in the content of instance, creating the intermediate nodes if needed.
+// In the case insensitive mode, the existing keys matching the key parts are used.
+func (c *Config) set(key string, value interface{}) {
+	if c.content == nil {
+		c.content = make(map[string]interface{})
+	}
+
+	content, parts := c.content, split(key)
+	for _, part := range parts[:len(parts)-1] {
+		part = c.resolve(content, part)
+		m, ok := content[part].(map[string]interface{})
+		if !ok {
+			m = make(map[string]interface{})
+			content[part] = m
+		}
+
+		content = m
+	}
+
+	content[c.resolve(content, parts[len(parts)-1])] = value
+}
+
+// effective deep copies the content of the key, replacing the values overridden by environment variables.
+func (c *Config) effective(content map[string]interface{}, key string) map[string]interface{} {
+	m := make(map[string]interface{}, len(content))
+	for k, v := range content {
+		nested := k
+		if key != "" {
+			nested = key + "." + k
+		}
+
+		if vs, ok := v.(map[string]interface{}); ok {
+			m[k] = c.effective(vs, nested)
+		} else if env, exists := os.LookupEnv(c.env(nested)); exists {
+			m[k] = env
+		} else {
+			m[k] = clone(v)
+		}
+	}
+
+	return m
+}
+
+// fresh creates a new empty instance sharing the settings of the current instance.
+func (c *Config) fresh() *Config {
+	f := c.derive("", make(map[string]interface{}), nil)
+	f.mutex, f.root = &sync.RWMutex{}, f
+	return f
+}
+
+// record records the load, so it is replayed by reload.
+func (c *Config) record(load func(*Config) error) {
+	c.lock()
+	defer c.unlock()
+
+	c.loads = append(c.loads, load)
+}
+
+// override records the value set for the key, so it is replayed by reload. It should be called while the instance is locked.
+// The values set before for the key, or the keys nested in it, are replaced, since the value overwrites them.
+func (c *Config) override(key string, value interface{}) {
+	if c.overrides == nil {
+		c.overrides = make(map[string]override)
+	}
+
+	for k := range c.overrides {
+		if strings.HasPrefix(k, key+".") {
+			delete(c.overrides, k)
+		}
+	}
+
+	c.sets++
+	c.overrides[key] = override{key: key, value: value, loads: len(c.loads), sequence: c.sets}
+}
+
+// watched returns the files loaded by LoadJSON so far.
+func (c *Config) watched() []string {
+	c.rlock()
+	defer c.runlock()
+
+	return append([]string(nil), c.filenames...)
+}
+
+// reload creates a new instance sharing the settings of the current instance, and replays the current loads
+// and the values set on it, in the order they are made.
+func (c *Config) reload() (*Config, error) {
+	c.rlock()
+	loads := append([]func(*Config) error(nil), c.loads...)
+	overrides := make([]override, 0, len(c.overrides))
+	for _, o := range c.overrides {
+		overrides = append(overrides, o)
+	}
+	c.runlock()
+
+	sort.Slice(overrides, func(i, j int) bool {
+		return overrides[i].sequence < overrides[j].sequence
+	})
+
+	r := c.fresh()
+	for i := 0; i <= len(loads); i++ {
+		for len(overrides) > 0 && overrides[0].loads <= i {
+			r.Set(overrides[0].key, overrides[0].value)
+			overrides = overrides[1:]
+		}
+
+		if i < len(loads) {
+			if e := loads[i](r); e != nil {
+				return nil, e
+			}
+		}
+	}
+
+	return r, nil
+}
+
+// expand expands the ${KEY} references of s if interpolation is enabled.
+// The depth is the number of references expanded so far to reach s, used to detect cyclic references.
+func (c *Config) expand(s string, depth int) (string, error) {
+	if !c.interpolate {
+		return s, nil
+	}
+
+	if depth > maxInterpolationDepth {
+		return "", errors.New(fmt.Sprintf("configuring: interpolation of %q is too deep", s))
+	}
+
+	var b strings.Builder
+	for {
+		i := strings.Index(s, "${")
+		if i < 0 {
+			b.WriteString(s)
+			break
+		}
+
+		if i > 0 && s[i-1] == '$' {
+			b.WriteString(s[:i-1])
+			b.WriteString("${")
+			s = s[i+2:]
+			continue
+		}
+
+		b.WriteString(s[:i])
+		j := strings.IndexByte(s[i:], '}')
+		if j < 0 {
+			if c.strict {
+				return "", errors.New(fmt.Sprintf("configuring: unterminated reference %q", s[i:]))
+			}
+
+			b.WriteString(s[i:])
+			break
+		}
+
+		key := s[i+2 : i+j]
+		if v, ok := c.reference(key); ok {
+			r, e := c.expand(v, depth+1)
+			if e != nil {
+				return "", e
+			}
+
+			b.WriteString(r)
+		} else if c.strict {
+			return "", errors.New(fmt.Sprintf("configuring: unresolved reference ${%s}", key))
+		} else {
+			b.WriteString(s[i : i+j+1])
+		}
+
+		s = s[i+j+1:]
+	}
+
+	return b.String(), nil
+}
+
+// reference resolves a referenced key, first as an environment variable and then as a key of loaded configuration.
+func (c *Config) reference(key string) (string, bool) {
+	if v, exists := os.LookupEnv(key); exists {
+		return v, true
+	}
+
+	switch v := c.root.Get(key).node.(type) {
+	case string, float64, json.Number, bool:
+		return fmt.Sprint(v), true
+	default:
+		return "", false
+	}
+}
+
+// signed converts the node to a signed integer of the bit size provided, named as kind in errors.
+func (c *Config) signed(bits int, kind string) (int64, error) {
+	if c.node == nil {
+		return 0, c.notFound()
+	}
+
+	node := c.node
+	if n, ok := node.(json.Number); ok {
+		node = number(n, false)
+	}
+
+	var v int64
+	switch n := node.(type) {
+	case int:
+		v = int64(n)
+	case int64:
+		v = n
+	case float64:
+		if n != math.Trunc(n) {
+			return 0, errors.New(fmt.Sprintf("configuring: %v to %s has fractional part", n, kind))
+		}
+
+		if n < -(1<<63) || n >= 1<<63 {
+			return 0, errors.New(fmt.Sprintf("configuring: %v overflows %s", n, kind))
+		}
+
+		v = int64(n)
+	default:
+		p, e := parseInt(c.StringOrElse(""))
+		if errors.Is(e, strconv.ErrRange) {
+			return 0, errors.New(fmt.Sprintf("configuring: %s overflows %s", c.StringOrElse(""), kind))
+		}
+
+		if e != nil {
+			return 0, errors.New(fmt.Sprintf("configuring: %T to %s not supported", c.node, kind))
+		}
+
+		v = p
+	}
+
+	if bits < 64 && (v < -(1<<(bits-1)) || v > 1<<(bits-1)-1) {
+		return 0, errors.New(fmt.Sprintf("configuring: %d overflows %s", v, kind))
+	}
+
+	return v, nil
+}
+
+// unsigned converts the node to an unsigned integer of the bit size provided, named as kind in errors.
+func (c *Config) unsigned(bits int, kind string) (uint64, error) {
+	if c.node == nil {
+		return 0, c.notFound()
+	}
+
+	node := c.node
+	if n, ok := node.(json.Number); ok {
+		node = number(n, true)
+	}
+
+	var v uint64
+	switch n := node.(type) {
+	case uint:
+		v = uint64(n)
+	case uint64:
+		v = n
+	case float64:
+		if n != math.Trunc(n) {
+			return 0, errors.New(fmt.Sprintf("configuring: %v to %s has fractional part", n, kind))
+		}
+
+		if n < 0 {
+			return 0, errors.New(fmt.Sprintf("configuring: %v to %s is negative", n, kind))
+		}
+
+		if n >= 1<<64 {
+			return 0, errors.New(fmt.Sprintf("configuring: %v overflows %s", n, kind))
+		}
+
+		v = uint64(n)
+	default:
+		p, e := parseUint(c.StringOrElse(""))
+		if errors.Is(e, strconv.ErrRange) {
+			return 0, errors.New(fmt.Sprintf("configuring: %s overflows %s", c.StringOrElse(""), kind))
+		}
+
+		if e != nil {
+			return 0, errors.New(fmt.Sprintf("configuring: %T to %s not supported", c.node, kind))
+		}
+
+		v = p
+	}
+
+	if bits < 64 && v > 1<<bits-1 {
+		return 0, errors.New(fmt.Sprintf("configuring: %d overflows %s", v, kind))
+	}
+
+	return v, nil
+}
+
+// number converts a JSON number to int64, or uint64 if unsigned, when it is an integer fitting in 64 bits,
+// otherwise to float64. So the integers beyond the precision of float64 are not rounded.
+func number(n json.Number, unsigned bool) interface{} {
+	if unsigned {
+		if v, e := strconv.ParseUint(n.String(), 10, 64); e == nil {
+			return v
+		}
+	} else if v, e := n.Int64(); e == nil {
+		return v
+	}
+
+	f, _ := n.Float64()
+	return f
+}
+
+// notFound returns the error determining the requested key not found, or the value is null.
+func (c *Config) notFound() error {
+	return &KeyError{Key: c.key}
+}
+
+// env converts a key to an appropriate environment variable format, considering the environment variable prefix.
+func (c *Config) env(key string) string {
+	if c.prefix == "" {
+		return asEnv(key)
+	}
+
+	return asEnv(c.prefix + "." + key)
+}
+
 // asEnv converts a key to an appropriate environment variable format.
 // For example it converts a to A, a.b to A_B, a_b to A_B, a.b_c to A_B_C and a_b.c to A_B_C.
 func asEnv(key string) string {
@@ -373,3 +1671,228 @@ func asEnv(key string) string {
 func split(key string) []string {
 	return strings.Split(key, ".")
 }
+
+// clone deep copies the value, copying the nested maps and slices recursively.
+func clone(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, nested := range v {
+			m[k] = clone(nested)
+		}
+
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(v))
+		for i, nested := range v {
+			s[i] = clone(nested)
+		}
+
+		return s
+	default:
+		return v
+	}
+}
+
+// parseInt parses the string as a 64-bit integer. Surrounding spaces, a leading sign, underscores between digits,
+// and 0x, 0o or 0b prefixes of hexadecimal, octal and binary integers are accepted.
+func parseInt(s string) (int64, error) {
+	return strconv.ParseInt(integer(s), 0, 64)
+}
+
+// parseUint parses the string as a 64-bit unsigned integer. Surrounding spaces, a leading plus sign,
+// underscores between digits, and 0x, 0o or 0b prefixes of hexadecimal, octal and binary integers are accepted.
+func parseUint(s string) (uint64, error) {
+	return strconv.ParseUint(strings.TrimPrefix(integer(s), "+"), 0, 64)
+}
+
+// parseFloat parses the string as a floating point of the bit size provided. Surrounding spaces are accepted.
+func parseFloat(s string, bits int) (float64, error) {
+	return strconv.ParseFloat(strings.TrimSpace(s), bits)
+}
+
+// integer trims the spaces of an integer string, and the leading zeros if it is not prefixed,
+// so strconv does not consider a decimal integer with leading zeros like 010 as an octal integer.
+func integer(s string) string {
+	s = strings.TrimSpace(s)
+
+	sign, digits := "", s
+	if strings.HasPrefix(digits, "+") || strings.HasPrefix(digits, "-") {
+		sign, digits = digits[:1], digits[1:]
+	}
+
+	if len(digits) > 1 && digits[0] == '0' && strings.ContainsAny(digits[1:2], "xXoObB") {
+		return s
+	}
+
+	if trimmed := strings.TrimLeft(digits, "0"); trimmed != digits {
+		if trimmed == "" {
+			trimmed = "0"
+		}
+
+		return sign + trimmed
+	}
+
+	return s
+}
+
+// normalize converts the value to the types of decoded JSON, or returns it as is if it is not encodable to JSON.
+// Like decode, numbers are converted to json.Number.
+func normalize(value interface{}) interface{} {
+	switch v := value.(type) {
+	case time.Duration:
+		return v.String()
+	case *url.URL:
+		return v.String()
+	case *net.IPNet:
+		return v.String()
+	}
+
+	data, e := json.Marshal(value)
+	if e != nil {
+		return value
+	}
+
+	var normalized interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if e := decoder.Decode(&normalized); e != nil {
+		return value
+	}
+
+	return normalized
+}
+
+// redact replaces the values of the key matched by sensitive patterns with "***", walking objects and arrays.
+// The elements of arrays are matched by their index, like servers.0.password. Objects and arrays are redacted
+// in place, so the value should be a copy. It returns the redacted value.
+func redact(value interface{}, key string, sensitive []string) (interface{}, error) {
+	if key != "" {
+		for _, pattern := range sensitive {
+			m, e := path.Match(pattern, key)
+			if e != nil {
+				return nil, fmt.Errorf("configuring: pattern %s: %w", pattern, e)
+			}
+
+			if m {
+				return "***", nil
+			}
+		}
+	}
+
+	nested := func(k string) string {
+		if key == "" {
+			return k
+		}
+
+		return key + "." + k
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for k, vs := range v {
+			r, e := redact(vs, nested(k), sensitive)
+			if e != nil {
+				return nil, e
+			}
+
+			v[k] = r
+		}
+	case []interface{}:
+		for i, vs := range v {
+			r, e := redact(vs, nested(strconv.Itoa(i)), sensitive)
+			if e != nil {
+				return nil, e
+			}
+
+			v[i] = r
+		}
+	}
+
+	return value, nil
+}
+
+// decode decodes the JSON object read from the reader. The filename if not empty is used to decorate the errors.
+// Numbers are decoded as json.Number, so the integers beyond the precision of float64 are not rounded.
+func decode(filename string, r io.Reader) (map[string]interface{}, error) {
+	data, e := ioutil.ReadAll(r)
+	if e != nil {
+		return nil, e
+	}
+
+	content := make(map[string]interface{})
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if e := decoder.Decode(&content); e != nil {
+		return nil, decodeError(filename, data, decoder, e)
+	}
+
+	if _, e := decoder.Token(); e != io.EOF {
+		if e == nil {
+			e = errTrailingData
+		}
+
+		return nil, decodeError(filename, data, decoder, e)
+	}
+
+	return content, nil
+}
+
+// decodeError decorates a JSON decoding error with the filename and, for syntax errors, the line and column
+// the problem occurred at, so a malformed configuration file can be fixed easily.
+func decodeError(filename string, data []byte, decoder *json.Decoder, e error) error {
+	prefix := "configuring: "
+	if filename != "" {
+		prefix += filename + ":"
+	}
+
+	var offset int64
+	switch v := e.(type) {
+	case *json.SyntaxError:
+		offset = v.Offset
+	case *json.UnmarshalTypeError:
+		offset = v.Offset
+	default:
+		switch e {
+		case io.EOF, io.ErrUnexpectedEOF:
+			offset = int64(len(data))
+		case errTrailingData:
+			offset = decoder.InputOffset()
+		default:
+			return fmt.Errorf("%s %w", strings.TrimSuffix(prefix, " "), e)
+		}
+	}
+
+	line, column := position(data, offset)
+	return fmt.Errorf("%s%d:%d: %w", prefix, line, column, e)
+}
+
+// position converts a byte offset of data to its line and column, both starting from 1.
+func position(data []byte, offset int64) (int, int) {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+
+	before := data[:offset]
+	line := bytes.Count(before, []byte{'\n'}) + 1
+	column := len(before) - (bytes.LastIndexByte(before, '\n') + 1)
+	if column == 0 {
+		column = 1
+	}
+
+	return line, column
+}
+
+// modifications describes the modification time and size of the files, used to detect changes.
+func modifications(filenames []string) string {
+	var b strings.Builder
+	for _, filename := range filenames {
+		if info, e := os.Stat(filename); e == nil {
+			b.WriteString(fmt.Sprintf("%d:%d;", info.ModTime().UnixNano(), info.Size()))
+		} else {
+			b.WriteString("-;")
+		}
+	}
+
+	return b.String()
+}
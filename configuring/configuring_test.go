@@ -0,0 +1,1290 @@
+package configuring
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// load creates a new instance loaded with the JSON data provided, failing the test on errors.
+func load(t *testing.T, data string) *Config {
+	t.Helper()
+
+	c, e := New().LoadJSONBytes([]byte(data))
+	if e != nil {
+		t.Fatalf("loading %s: %v", data, e)
+	}
+
+	return c
+}
+
+// setenv sets the environment variable for the duration of the test.
+func setenv(t *testing.T, key, value string) {
+	t.Helper()
+
+	previous, exists := os.LookupEnv(key)
+	if e := os.Setenv(key, value); e != nil {
+		t.Fatal(e)
+	}
+
+	t.Cleanup(func() {
+		if exists {
+			_ = os.Setenv(key, previous)
+		} else {
+			_ = os.Unsetenv(key)
+		}
+	})
+}
+
+func TestExists(t *testing.T) {
+	setenv(t, "CT_EXISTS_ENV", "")
+	c := load(t, `{"ct": {"exists": {"json": "v", "null": null, "empty": ""}}}`)
+
+	tests := []struct {
+		key    string
+		exists bool
+	}{
+		{"ct.exists.env", true},
+		{"ct.exists.json", true},
+		{"ct.exists.empty", true},
+		{"ct.exists.null", false},
+		{"ct.exists.absent", false},
+	}
+
+	for _, test := range tests {
+		if exists := c.Get(test.key).Exists(); exists != test.exists {
+			t.Errorf("Get(%q).Exists() = %v, want %v", test.key, exists, test.exists)
+		}
+	}
+}
+
+// tempDir creates a temporary directory removed after the test.
+func tempDir(t *testing.T) string {
+	t.Helper()
+
+	dir, e := ioutil.TempDir("", "configuring")
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+	return dir
+}
+
+// writeFile writes the data to the file in the directory and returns its path.
+func writeFile(t *testing.T, dir, name, data string) string {
+	t.Helper()
+
+	filename := filepath.Join(dir, name)
+	if e := ioutil.WriteFile(filename, []byte(data), 0600); e != nil {
+		t.Fatal(e)
+	}
+
+	return filename
+}
+
+func TestLoadJSONSyntaxErrorPosition(t *testing.T) {
+	dir := tempDir(t)
+	tests := []struct {
+		data     string
+		position string
+	}{
+		{"{\n  \"a\": 1,\n  \"b\": ]\n}", ":3:8: "},
+		{"{\n  \"a\": 1\n", ":3:1: "},
+		{"{\"a\": 1}\n{\"b\": 2}", ":2:1: "},
+		{"{\n  \"a\": \"x\"\n  \"b\": 1\n}", ":3:3: "},
+	}
+
+	for _, test := range tests {
+		filename := writeFile(t, dir, "config.json", test.data)
+		_, e := New().LoadJSON(filename)
+		if e == nil || !strings.Contains(e.Error(), filename+test.position) {
+			t.Errorf("LoadJSON(%q) error = %v, want position %s", test.data, e, test.position)
+		}
+	}
+
+	if _, e := New().LoadJSONBytes([]byte("[1, 2]")); e == nil {
+		t.Error("LoadJSONBytes of an array succeeded, want an error")
+	}
+}
+
+func TestUnmarshal(t *testing.T) {
+	c := load(t, `{"db": {"host": "localhost", "port": 5432, "replicas": ["a", "b"]}}`)
+
+	var db struct {
+		Host     string   `json:"host"`
+		Port     int      `json:"port"`
+		Replicas []string `json:"replicas"`
+	}
+
+	if e := c.Get("db").Unmarshal(&db); e != nil {
+		t.Fatal(e)
+	}
+
+	if db.Host != "localhost" || db.Port != 5432 || !reflect.DeepEqual(db.Replicas, []string{"a", "b"}) {
+		t.Errorf("Unmarshal = %+v", db)
+	}
+
+	if e := c.Get("db").Unmarshal(db); e == nil {
+		t.Error("Unmarshal to non-pointer succeeded, want an error")
+	}
+
+	if e := c.Get("absent").Unmarshal(&db); !errors.Is(e, ErrNotFoundOrNullValue) {
+		t.Errorf("Unmarshal of absent key error = %v, want ErrNotFoundOrNullValue", e)
+	}
+}
+
+func TestSliceOfIntAndFloat64(t *testing.T) {
+	c := load(t, `{"ints": [1, 2, 3], "fractional": [1, 1.5], "overflow": [1, 1e20], "strings": ["1", "x"],
+		"floats": [1.5, 2], "empty": [], "scalar": 1}`)
+
+	if is, e := c.Get("ints").SliceOfInt(); e != nil || !reflect.DeepEqual(is, []int{1, 2, 3}) {
+		t.Errorf("SliceOfInt = %v, %v", is, e)
+	}
+
+	if is, e := c.Get("empty").SliceOfInt(); e != nil || len(is) != 0 {
+		t.Errorf("SliceOfInt of empty array = %v, %v", is, e)
+	}
+
+	for key, element := range map[string]string{"fractional": "element 1", "overflow": "element 1", "strings": "element 1"} {
+		if _, e := c.Get(key).SliceOfInt(); e == nil || !strings.Contains(e.Error(), element) {
+			t.Errorf("SliceOfInt of %s error = %v, want %s", key, e, element)
+		}
+	}
+
+	if _, e := c.Get("scalar").SliceOfInt(); e == nil {
+		t.Error("SliceOfInt of scalar succeeded, want an error")
+	}
+
+	if is := c.Get("fractional").SliceOfIntOrElse([]int{7}); !reflect.DeepEqual(is, []int{7}) {
+		t.Errorf("SliceOfIntOrElse = %v, want the default value", is)
+	}
+
+	if fs, e := c.Get("floats").SliceOfFloat64(); e != nil || !reflect.DeepEqual(fs, []float64{1.5, 2}) {
+		t.Errorf("SliceOfFloat64 = %v, %v", fs, e)
+	}
+
+	if _, e := c.Get("strings").SliceOfFloat64(); e == nil || !strings.Contains(e.Error(), "element 0") {
+		t.Errorf("SliceOfFloat64 of strings error = %v, want element 0", e)
+	}
+
+	if fs := c.Get("strings").SliceOfFloat64OrElse([]float64{7}); !reflect.DeepEqual(fs, []float64{7}) {
+		t.Errorf("SliceOfFloat64OrElse = %v, want the default value", fs)
+	}
+}
+
+func TestInterpolation(t *testing.T) {
+	setenv(t, "CT_INTERPOLATION_HOST", "example.com")
+	c := load(t, `{
+		"server": {"port": 8080, "url": "https://${CT_INTERPOLATION_HOST}:${server.port}"},
+		"chain": {"a": "${chain.b}/a", "b": "${chain.c}/b", "c": "c"},
+		"escaped": "$${CT_INTERPOLATION_HOST}",
+		"unresolved": "${ct.interpolation.absent}",
+		"unterminated": "${server.port",
+		"cycle": {"a": "${cycle.b}", "b": "${cycle.a}"}
+	}`)
+
+	if s, _ := c.Get("server.url").String(); s != "https://${CT_INTERPOLATION_HOST}:${server.port}" {
+		t.Errorf("String without interpolation = %q", s)
+	}
+
+	c.EnableInterpolation(false)
+	tests := map[string]string{
+		"server.url":   "https://example.com:8080",
+		"chain.a":      "c/b/a",
+		"escaped":      "${CT_INTERPOLATION_HOST}",
+		"unresolved":   "${ct.interpolation.absent}",
+		"unterminated": "${server.port",
+	}
+
+	for key, want := range tests {
+		if s, e := c.Get(key).String(); e != nil || s != want {
+			t.Errorf("Get(%q).String() = %q, %v, want %q", key, s, e, want)
+		}
+	}
+
+	if _, e := c.Get("cycle.a").String(); e == nil {
+		t.Error("String of cyclic references succeeded, want an error")
+	}
+
+	c.EnableInterpolation(true)
+	for _, key := range []string{"unresolved", "unterminated"} {
+		if _, e := c.Get(key).String(); e == nil {
+			t.Errorf("strict Get(%q).String() succeeded, want an error", key)
+		}
+
+		if s := c.Get(key).StringOrElse("default"); s != "default" {
+			t.Errorf("strict Get(%q).StringOrElse() = %q, want the default value", key, s)
+		}
+	}
+}
+
+func TestWithEnvPrefix(t *testing.T) {
+	setenv(t, "CT_DB_USER", "prefixed")
+	setenv(t, "DB_USER", "unprefixed")
+
+	c := load(t, `{"db": {"user": "json"}}`)
+	if s, _ := c.Get("db.user").String(); s != "unprefixed" {
+		t.Errorf("without prefix = %q, want unprefixed", s)
+	}
+
+	for _, prefix := range []string{"CT", "CT_", "CT."} {
+		if s, _ := c.WithEnvPrefix(prefix).Get("db.user").String(); s != "prefixed" {
+			t.Errorf("with prefix %q = %q, want prefixed", prefix, s)
+		}
+	}
+
+	if s, _ := c.WithEnvPrefix("").Get("db.user").String(); s != "unprefixed" {
+		t.Errorf("with empty prefix = %q, want unprefixed", s)
+	}
+}
+
+func TestCertKeyPair(t *testing.T) {
+	if _, e := load(t, `{}`).CertKeyPair(); !errors.Is(e, ErrNotFoundOrNullValue) || !strings.Contains(e.Error(), "tls.cert_file") {
+		t.Errorf("CertKeyPair without cert_file error = %v", e)
+	}
+
+	c := load(t, `{"tls": {"cert_file": "/nonexistent/cert.pem", "key_file": "/nonexistent/key.pem"}}`)
+	if _, e := c.CertKeyPair(); !errors.Is(e, os.ErrNotExist) {
+		t.Errorf("CertKeyPair with missing files error = %v, want os.ErrNotExist", e)
+	}
+}
+
+func TestConcurrentGetAndLoad(t *testing.T) {
+	c := load(t, `{"db": {"user": "a", "hosts": ["x", "y"]}}`)
+	db := c.Get("db")
+
+	wg := &sync.WaitGroup{}
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			c.Set(fmt.Sprintf("db.key%d", i), i)
+			if _, e := c.LoadJSONBytes([]byte(fmt.Sprintf(`{"round": %d}`, i))); e != nil {
+				t.Error(e)
+			}
+		}
+	}()
+
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			c.Get("db.user").StringOrElse("")
+			c.Get("round").IntOrElse(0)
+			db.Get("user").StringOrElse("")
+			_, _ = db.MapOfString()
+			_ = db.Unmarshal(&map[string]interface{}{})
+			_, _ = c.ToJSON()
+			_ = c.Clone()
+		}
+	}()
+
+	wg.Wait()
+	if n, _ := c.Get("db.key99").Int(); n != 99 {
+		t.Errorf("db.key99 = %d, want 99", n)
+	}
+}
+
+func TestConcurrentEnvAndSub(t *testing.T) {
+	setenv(t, "CT_CONCURRENT_USER", "env")
+	c := load(t, `{"ct": {"concurrent": {"user": "json", "pool": {"size": 5}}}}`)
+
+	wg := &sync.WaitGroup{}
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			c.Set(fmt.Sprintf("ct.concurrent.key%d", i), i)
+		}
+	}()
+
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			if s, _ := c.Get("ct.concurrent.user").String(); s != "env" {
+				t.Errorf("Get of env overridden key = %q, want env", s)
+			}
+
+			if n, _ := c.Sub("ct.concurrent").Get("pool.size").Int(); n != 5 {
+				t.Errorf("Sub Get = %d, want 5", n)
+			}
+
+			c.GetAny().Exists()
+		}
+	}()
+
+	wg.Wait()
+}
+
+func TestZeroValue(t *testing.T) {
+	setenv(t, "CT_ZERO", "env")
+
+	var c Config
+	if s, _ := c.Get("ct.zero").String(); s != "env" {
+		t.Errorf("zero value Get = %q, want env", s)
+	}
+
+	c.Set("a.b", "v")
+	if s, _ := c.Get("a.b").String(); s != "v" {
+		t.Errorf("zero value Get after Set = %q, want v", s)
+	}
+
+	var d Config
+	if _, e := d.LoadJSONBytes([]byte(`{"n": 1}`)); e != nil {
+		t.Fatal(e)
+	}
+
+	if n, _ := d.Clone().Get("n").Int(); n != 1 {
+		t.Errorf("zero value Clone Get = %d, want 1", n)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	dir := tempDir(t)
+	filename := writeFile(t, dir, "config.json", `{"port": 8080, "name": "file"}`)
+	writeFile(t, dir, "db.password", "secret\n")
+
+	c, e := New().LoadJSON(filename)
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	if _, e := c.LoadJSONBytes([]byte(`{"name": "bytes"}`)); e != nil {
+		t.Fatal(e)
+	}
+
+	secrets := filepath.Join(dir, "secrets")
+	if e := os.Mkdir(secrets, 0700); e != nil {
+		t.Fatal(e)
+	}
+	writeFile(t, secrets, "db.password", "secret\n")
+
+	if _, e := c.LoadSecretsDir(secrets); e != nil {
+		t.Fatal(e)
+	}
+	c.Set("mode", "set")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	reloaded := make(chan *Config, 1)
+	go func() {
+		_ = c.Watch(ctx, func(r *Config) {
+			select {
+			case reloaded <- r:
+			default:
+			}
+		})
+	}()
+
+	// Let the watcher record the initial modification time before rewriting the file.
+	time.Sleep(2 * watchInterval)
+	c.Set("late", "set")
+	writeFile(t, dir, "config.json", `{"port": 9090, "name": "file"}`)
+
+	select {
+	case r := <-reloaded:
+		tests := map[string]string{"name": "bytes", "db.password": "secret", "mode": "set", "late": "set"}
+		for key, want := range tests {
+			if s, _ := r.Get(key).String(); s != want {
+				t.Errorf("reloaded Get(%q) = %q, want %q", key, s, want)
+			}
+		}
+
+		if n, _ := r.Get("port").Int(); n != 9090 {
+			t.Errorf("reloaded port = %d, want 9090", n)
+		}
+	case <-ctx.Done():
+		t.Fatal("onChange is not called after the file is changed")
+	}
+
+	if n, _ := c.Get("port").Int(); n != 8080 {
+		t.Errorf("original port = %d, want 8080", n)
+	}
+
+	if e := New().Watch(ctx, func(*Config) {}); e == nil {
+		t.Error("Watch without files succeeded, want an error")
+	}
+}
+
+func TestReload(t *testing.T) {
+	c := load(t, `{"a": "loaded", "b": "loaded"}`)
+	c.Set("a", "set")
+	c.Set("c", map[string]interface{}{"x": 1})
+	c.Set("c.y", 2)
+	c.Set("c", map[string]interface{}{"z": 3})
+	if _, e := c.LoadJSONBytes([]byte(`{"b": "reloaded", "d": "loaded"}`)); e != nil {
+		t.Fatal(e)
+	}
+	c.Set("d", "set")
+
+	for i := 0; i < 100; i++ {
+		c.Set("counter", i)
+	}
+
+	if len(c.overrides) != 4 {
+		t.Errorf("overrides = %d, want one for each key set", len(c.overrides))
+	}
+
+	r, e := c.reload()
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	tests := map[string]string{"a": "set", "b": "reloaded", "d": "set"}
+	for key, want := range tests {
+		if s, _ := r.Get(key).String(); s != want {
+			t.Errorf("reloaded Get(%q) = %q, want %q", key, s, want)
+		}
+	}
+
+	if !r.Get("c.z").Exists() || r.Get("c.x").Exists() || r.Get("c.y").Exists() {
+		t.Errorf("reloaded c = %v, want only z", r.Get("c").node)
+	}
+
+	if n, _ := r.Get("counter").Int(); n != 99 {
+		t.Errorf("reloaded counter = %d, want 99", n)
+	}
+}
+
+func TestSet(t *testing.T) {
+	setenv(t, "CT_SET_ENV", "env")
+	c := load(t, `{"ct": {"set": {"json": "json", "env": "json"}}}`)
+
+	c.Set("ct.set.json", "set")
+	c.Set("ct.set.env", "set")
+	c.Set("ct.set.new.nested", "nested")
+	c.Set("port", 8080)
+	c.Set("hosts", []string{"a", "b"})
+	c.Set("timeout", 3*time.Second)
+	c.Set("labels", map[string]string{"app": "lib"})
+
+	strs := map[string]string{"ct.set.json": "set", "ct.set.env": "env", "ct.set.new.nested": "nested", "labels.app": "lib"}
+	for key, want := range strs {
+		if s, _ := c.Get(key).String(); s != want {
+			t.Errorf("Get(%q) = %q, want %q", key, s, want)
+		}
+	}
+
+	if n, e := c.Get("port").Uint16(); e != nil || n != 8080 {
+		t.Errorf("Uint16 after Set = %d, %v", n, e)
+	}
+
+	if f, e := c.Get("port").Float64(); e != nil || f != 8080 {
+		t.Errorf("Float64 after Set = %v, %v", f, e)
+	}
+
+	if ss, e := c.Get("hosts").SliceOfString(); e != nil || !reflect.DeepEqual(ss, []string{"a", "b"}) {
+		t.Errorf("SliceOfString after Set = %v, %v", ss, e)
+	}
+
+	if d, e := c.Get("timeout").Duration(); e != nil || d != 3*time.Second {
+		t.Errorf("Duration after Set = %v, %v", d, e)
+	}
+}
+
+func TestSetReplacesNodeType(t *testing.T) {
+	c := load(t, `{}`)
+
+	c.Set("a", 1)
+	c.Set("a.b", 2)
+	if n, e := c.Get("a.b").Int(); e != nil || n != 2 {
+		t.Errorf("a.b after replacing scalar = %d, %v, want 2", n, e)
+	}
+
+	if m, ok := c.Get("a").node.(map[string]interface{}); !ok || len(m) != 1 {
+		t.Errorf("a after replacing scalar = %v, want an object with b", c.Get("a").node)
+	}
+
+	c.Set("a", 3)
+	if n, e := c.Get("a").Int(); e != nil || n != 3 {
+		t.Errorf("a after replacing object = %d, %v, want 3", n, e)
+	}
+
+	if c.Get("a.b").Exists() {
+		t.Error("a.b exists after replacing its parent with a scalar")
+	}
+
+	c.Set("x", map[string]int{"y": 1})
+	c.Set("x.y.z", "nested")
+	if s, _ := c.Get("x.y.z").String(); s != "nested" {
+		t.Errorf("x.y.z = %q, want nested", s)
+	}
+
+	r, e := c.reload()
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	if n, _ := r.Get("a").Int(); n != 3 || r.Get("a.b").Exists() {
+		t.Errorf("reloaded a = %v, want 3", r.Get("a").node)
+	}
+
+	if s, _ := r.Get("x.y.z").String(); s != "nested" {
+		t.Errorf("reloaded x.y.z = %q, want nested", s)
+	}
+}
+
+func TestSub(t *testing.T) {
+	setenv(t, "CT_SUB_USER", "env")
+	c := load(t, `{"db": {"user": "json", "pool": {"size": 5}}}`)
+
+	db := c.Sub("db")
+	if n, _ := db.Get("pool.size").Int(); n != 5 {
+		t.Errorf("Sub Get = %d, want 5", n)
+	}
+
+	db.Set("user", "changed")
+	if s, _ := c.Get("db.user").String(); s != "json" {
+		t.Errorf("parent after Set on Sub = %q, want json", s)
+	}
+
+	if s, _ := c.WithEnvPrefix("CT_SUB").Sub("db").Get("user").String(); s != "env" {
+		t.Errorf("Sub with prefix = %q, want env", s)
+	}
+
+	if c.Sub("db.user").Get("x").Exists() || c.Sub("absent").Get("x").Exists() {
+		t.Error("Sub of non-object node is not empty")
+	}
+}
+
+func TestRange(t *testing.T) {
+	c := load(t, `{"port": 8080, "ratio": 0.5}`)
+
+	if n, e := c.Get("port").IntInRange(1, 65535); e != nil || n != 8080 {
+		t.Errorf("IntInRange = %d, %v", n, e)
+	}
+
+	if _, e := c.Get("port").IntInRange(1, 1024); e == nil {
+		t.Error("IntInRange above maximum succeeded, want an error")
+	}
+
+	if _, e := c.Get("port").IntInRange(10000, 20000); e == nil {
+		t.Error("IntInRange below minimum succeeded, want an error")
+	}
+
+	if f, e := c.Get("ratio").Float64InRange(0, 1); e != nil || f != 0.5 {
+		t.Errorf("Float64InRange = %v, %v", f, e)
+	}
+
+	if _, e := c.Get("ratio").Float64InRange(0.6, 1); e == nil {
+		t.Error("Float64InRange below minimum succeeded, want an error")
+	}
+}
+
+func TestRangeBoundaries(t *testing.T) {
+	maxInt := int(^uint(0) >> 1)
+	minInt := -maxInt - 1
+
+	tests := []struct {
+		value    interface{}
+		min, max int
+		want     int
+		err      string
+	}{
+		{float64(1), 1, 10, 1, ""},
+		{float64(10), 1, 10, 10, ""},
+		{float64(0), 1, 10, 0, "less than minimum 1"},
+		{float64(11), 1, 10, 0, "greater than maximum 10"},
+		{strconv.Itoa(maxInt), minInt, maxInt, maxInt, ""},
+		{strconv.Itoa(minInt), minInt, maxInt, minInt, ""},
+		{strconv.FormatUint(uint64(maxInt)+1, 10), minInt, maxInt, 0, "overflows int"},
+		{"-" + strconv.FormatUint(uint64(maxInt)+2, 10), minInt, maxInt, 0, "overflows int"},
+		{"x", 1, 10, 0, "to int not supported"},
+		{"12abc", 1, 10, 0, "to int not supported"},
+		{true, 1, 10, 0, "to int not supported"},
+	}
+
+	for _, test := range tests {
+		c := New()
+		c.Set("v", test.value)
+		n, e := c.Get("v").IntInRange(test.min, test.max)
+		if test.err == "" {
+			if e != nil || n != test.want {
+				t.Errorf("IntInRange(%d, %d) of %v = %d, %v, want %d", test.min, test.max, test.value, n, e, test.want)
+			}
+		} else if e == nil || !strings.Contains(e.Error(), test.err) {
+			t.Errorf("IntInRange(%d, %d) of %v error = %v, want %q", test.min, test.max, test.value, e, test.err)
+		}
+	}
+
+	floats := []struct {
+		value    interface{}
+		min, max float64
+		err      string
+	}{
+		{0.0, 0, 1, ""},
+		{1.0, 0, 1, ""},
+		{-0.001, 0, 1, "less than minimum 0"},
+		{1.001, 0, 1, "greater than maximum 1"},
+		{math.MaxFloat64, -math.MaxFloat64, math.MaxFloat64, ""},
+		{"x", 0, 1, "to float64 not supported"},
+	}
+
+	for _, test := range floats {
+		c := New()
+		c.Set("v", test.value)
+		_, e := c.Get("v").Float64InRange(test.min, test.max)
+		if test.err == "" {
+			if e != nil {
+				t.Errorf("Float64InRange(%v, %v) of %v = %v", test.min, test.max, test.value, e)
+			}
+		} else if e == nil || !strings.Contains(e.Error(), test.err) {
+			t.Errorf("Float64InRange(%v, %v) of %v error = %v, want %q", test.min, test.max, test.value, e, test.err)
+		}
+	}
+
+	if _, e := New().Get("absent").IntInRange(1, 10); !errors.Is(e, ErrNotFoundOrNullValue) {
+		t.Errorf("IntInRange of absent key error = %v, want ErrNotFoundOrNullValue", e)
+	}
+}
+
+func TestStringOneOf(t *testing.T) {
+	c := load(t, `{"level": "info"}`)
+
+	if s, e := c.Get("level").StringOneOf("debug", "info"); e != nil || s != "info" {
+		t.Errorf("StringOneOf = %q, %v", s, e)
+	}
+
+	if _, e := c.Get("level").StringOneOf("INFO", "debug"); e == nil || !strings.Contains(e.Error(), "[INFO, debug]") {
+		t.Errorf("StringOneOf not allowed error = %v", e)
+	}
+
+	if _, e := c.Get("level").StringOneOf(); e == nil || !strings.Contains(e.Error(), "[]") {
+		t.Errorf("StringOneOf without allowed values error = %v", e)
+	}
+}
+
+func TestStringOneOfEmptyAndMissing(t *testing.T) {
+	c := load(t, `{"empty": "", "null": null}`)
+
+	if s, e := c.Get("empty").StringOneOf("", "info"); e != nil || s != "" {
+		t.Errorf("StringOneOf of allowed empty value = %q, %v", s, e)
+	}
+
+	if _, e := c.Get("empty").StringOneOf("info"); e == nil {
+		t.Error("StringOneOf of not allowed empty value succeeded, want an error")
+	}
+
+	for _, key := range []string{"null", "absent"} {
+		if _, e := c.Get(key).StringOneOf("", "info"); !errors.Is(e, ErrNotFoundOrNullValue) {
+			t.Errorf("StringOneOf of %s error = %v, want ErrNotFoundOrNullValue", key, e)
+		}
+	}
+}
+
+func TestMapOfString(t *testing.T) {
+	c := load(t, `{"labels": {"app": "lib", "tier": "backend"}, "mixed": {"a": "x", "b": 1}, "scalar": "x"}`)
+
+	if m, e := c.Get("labels").MapOfString(); e != nil || !reflect.DeepEqual(m, map[string]string{"app": "lib", "tier": "backend"}) {
+		t.Errorf("MapOfString = %v, %v", m, e)
+	}
+
+	for _, key := range []string{"mixed", "scalar"} {
+		if _, e := c.Get(key).MapOfString(); e == nil {
+			t.Errorf("MapOfString of %s succeeded, want an error", key)
+		}
+	}
+
+	if m := c.Get("absent").MapOfStringOrElse(map[string]string{"d": "v"}); m["d"] != "v" {
+		t.Errorf("MapOfStringOrElse = %v, want the default value", m)
+	}
+}
+
+func TestKeyError(t *testing.T) {
+	c := load(t, `{"db": {}}`)
+
+	_, e := c.Get("db").Get("user").String()
+	var keyError *KeyError
+	if !errors.As(e, &keyError) || keyError.Key != "db.user" {
+		t.Fatalf("error = %v, want KeyError of db.user", e)
+	}
+
+	if !errors.Is(e, ErrNotFoundOrNullValue) || !strings.Contains(e.Error(), "db.user") {
+		t.Errorf("error = %v, want to wrap ErrNotFoundOrNullValue and contain the key", e)
+	}
+}
+
+func TestNumericAccessors(t *testing.T) {
+	c := load(t, `{"v": 0}`)
+
+	tests := []struct {
+		value interface{}
+		get   func(*Config) (interface{}, error)
+		want  interface{}
+		fails bool
+	}{
+		{float64(42), func(c *Config) (interface{}, error) { return c.Int() }, 42, false},
+		{10.5, func(c *Config) (interface{}, error) { return c.Int() }, nil, true},
+		{1e20, func(c *Config) (interface{}, error) { return c.Int64() }, nil, true},
+		{float64(-1), func(c *Config) (interface{}, error) { return c.Uint() }, nil, true},
+		{int64(9007199254740993), func(c *Config) (interface{}, error) { return c.Int64() }, int64(9007199254740993), false},
+		{uint64(math.MaxUint64), func(c *Config) (interface{}, error) { return c.Uint64() }, uint64(math.MaxUint64), false},
+	}
+
+	for _, test := range tests {
+		c.Set("v", test.value)
+		v, e := test.get(c.Get("v"))
+		if test.fails {
+			if e == nil {
+				t.Errorf("%v: got %v, want an error", test.value, v)
+			}
+		} else if e != nil || v != test.want {
+			t.Errorf("%v: got %v (%T), %v, want %v (%T)", test.value, v, v, e, test.want, test.want)
+		}
+	}
+}
+
+func TestNumericPrecision(t *testing.T) {
+	c := load(t, `{
+		"above": 9007199254740993,
+		"max": 9223372036854775807,
+		"min": -9223372036854775808,
+		"over": 9223372036854775808,
+		"umax": 18446744073709551615,
+		"uover": 18446744073709551616,
+		"exp": 1e3,
+		"fraction": 1.5
+	}`)
+
+	tests := []struct {
+		key   string
+		get   func(*Config) (interface{}, error)
+		want  interface{}
+		fails bool
+	}{
+		{"above", func(c *Config) (interface{}, error) { return c.Int64() }, int64(9007199254740993), false},
+		{"above", func(c *Config) (interface{}, error) { return c.Uint64() }, uint64(9007199254740993), false},
+		{"max", func(c *Config) (interface{}, error) { return c.Int64() }, int64(math.MaxInt64), false},
+		{"min", func(c *Config) (interface{}, error) { return c.Int64() }, int64(math.MinInt64), false},
+		{"min", func(c *Config) (interface{}, error) { return c.Uint64() }, nil, true},
+		{"over", func(c *Config) (interface{}, error) { return c.Int64() }, nil, true},
+		{"over", func(c *Config) (interface{}, error) { return c.Uint64() }, uint64(1 << 63), false},
+		{"umax", func(c *Config) (interface{}, error) { return c.Uint64() }, uint64(math.MaxUint64), false},
+		{"uover", func(c *Config) (interface{}, error) { return c.Uint64() }, nil, true},
+		{"exp", func(c *Config) (interface{}, error) { return c.Int() }, 1000, false},
+		{"fraction", func(c *Config) (interface{}, error) { return c.Int() }, nil, true},
+		{"fraction", func(c *Config) (interface{}, error) { return c.Float64() }, 1.5, false},
+		{"fraction", func(c *Config) (interface{}, error) { return c.Float32() }, float32(1.5), false},
+	}
+
+	for _, test := range tests {
+		v, e := test.get(c.Get(test.key))
+		if test.fails {
+			if e == nil {
+				t.Errorf("%s: got %v, want an error", test.key, v)
+			}
+		} else if e != nil || v != test.want {
+			t.Errorf("%s: got %v (%T), %v, want %v (%T)", test.key, v, v, e, test.want, test.want)
+		}
+	}
+
+	c.Set("id", int64(9007199254740993))
+	if v, e := c.Get("id").Int64(); e != nil || v != 9007199254740993 {
+		t.Errorf("Int64 of id set = %d, %v, want 9007199254740993", v, e)
+	}
+
+	c.Set("ids", map[string]interface{}{"above": uint64(9007199254740993), "max": int64(math.MaxInt64)})
+	var target struct {
+		Above uint64 `json:"above"`
+		Max   int64  `json:"max"`
+	}
+	if e := c.Get("ids").Unmarshal(&target); e != nil || target.Above != 9007199254740993 || target.Max != math.MaxInt64 {
+		t.Errorf("Unmarshal = %+v, %v", target, e)
+	}
+}
+
+func TestSizedAccessors(t *testing.T) {
+	c := load(t, `{"v": 0}`)
+
+	tests := []struct {
+		value int64
+		get   func(*Config) (interface{}, error)
+		want  interface{}
+		fails bool
+	}{
+		{math.MaxInt8, func(c *Config) (interface{}, error) { return c.Int8() }, int8(math.MaxInt8), false},
+		{math.MaxInt8 + 1, func(c *Config) (interface{}, error) { return c.Int8() }, nil, true},
+		{math.MinInt8, func(c *Config) (interface{}, error) { return c.Int8() }, int8(math.MinInt8), false},
+		{math.MinInt8 - 1, func(c *Config) (interface{}, error) { return c.Int8() }, nil, true},
+		{math.MaxInt16, func(c *Config) (interface{}, error) { return c.Int16() }, int16(math.MaxInt16), false},
+		{math.MaxInt16 + 1, func(c *Config) (interface{}, error) { return c.Int16() }, nil, true},
+		{math.MinInt16, func(c *Config) (interface{}, error) { return c.Int16() }, int16(math.MinInt16), false},
+		{math.MinInt16 - 1, func(c *Config) (interface{}, error) { return c.Int16() }, nil, true},
+		{math.MaxInt32, func(c *Config) (interface{}, error) { return c.Int32() }, int32(math.MaxInt32), false},
+		{math.MaxInt32 + 1, func(c *Config) (interface{}, error) { return c.Int32() }, nil, true},
+		{math.MinInt32, func(c *Config) (interface{}, error) { return c.Int32() }, int32(math.MinInt32), false},
+		{math.MinInt32 - 1, func(c *Config) (interface{}, error) { return c.Int32() }, nil, true},
+		{math.MaxUint8, func(c *Config) (interface{}, error) { return c.Uint8() }, uint8(math.MaxUint8), false},
+		{math.MaxUint8 + 1, func(c *Config) (interface{}, error) { return c.Uint8() }, nil, true},
+		{-1, func(c *Config) (interface{}, error) { return c.Uint8() }, nil, true},
+		{math.MaxUint16, func(c *Config) (interface{}, error) { return c.Uint16() }, uint16(math.MaxUint16), false},
+		{math.MaxUint16 + 1, func(c *Config) (interface{}, error) { return c.Uint16() }, nil, true},
+		{-1, func(c *Config) (interface{}, error) { return c.Uint16() }, nil, true},
+		{math.MaxUint32, func(c *Config) (interface{}, error) { return c.Uint32() }, uint32(math.MaxUint32), false},
+		{math.MaxUint32 + 1, func(c *Config) (interface{}, error) { return c.Uint32() }, nil, true},
+		{-1, func(c *Config) (interface{}, error) { return c.Uint32() }, nil, true},
+		{0, func(c *Config) (interface{}, error) { return c.Uint32() }, uint32(0), false},
+	}
+
+	for _, test := range tests {
+		c.Set("v", test.value)
+		v, e := test.get(c.Get("v"))
+		if test.fails {
+			if e == nil {
+				t.Errorf("%d: got %v (%T), want an error", test.value, v, v)
+			}
+		} else if e != nil || v != test.want {
+			t.Errorf("%d: got %v (%T), %v, want %v (%T)", test.value, v, v, e, test.want, test.want)
+		}
+	}
+}
+
+func TestGetAny(t *testing.T) {
+	c := load(t, `{"port": 80, "server": {"port": null}}`)
+
+	if n, _ := c.GetAny("server.port", "port").Int(); n != 80 {
+		t.Errorf("GetAny = %d, want 80", n)
+	}
+
+	if c.GetAny("a", "b").Exists() || c.GetAny().Exists() {
+		t.Error("GetAny of absent keys exists")
+	}
+
+	if _, e := c.GetAny("a", "b").String(); e == nil || !strings.Contains(e.Error(), "key a ") {
+		t.Errorf("GetAny of absent keys error = %v, want the first key", e)
+	}
+}
+
+func TestIPAndCIDR(t *testing.T) {
+	c := load(t, `{"ip": "10.0.0.1", "ip6": "::1", "cidr": "10.0.0.0/8", "bad": "10.0.0", "null": null}`)
+
+	if ip, e := c.Get("ip").IP(); e != nil || ip.String() != "10.0.0.1" {
+		t.Errorf("IP = %v, %v", ip, e)
+	}
+
+	if ip, e := c.Get("ip6").IP(); e != nil || ip.String() != "::1" {
+		t.Errorf("IPv6 = %v, %v", ip, e)
+	}
+
+	if n, e := c.Get("cidr").CIDR(); e != nil || n.String() != "10.0.0.0/8" {
+		t.Errorf("CIDR = %v, %v", n, e)
+	}
+
+	if _, e := c.Get("bad").IP(); e == nil {
+		t.Error("IP of invalid address succeeded, want an error")
+	}
+
+	if _, e := c.Get("ip").CIDR(); e == nil {
+		t.Error("CIDR of address succeeded, want an error")
+	}
+
+	if ip := c.Get("bad").IPOrElse(nil); ip != nil {
+		t.Errorf("IPOrElse = %v, want the default value", ip)
+	}
+
+	for _, key := range []string{"null", "absent"} {
+		if _, e := c.Get(key).IP(); !errors.Is(e, ErrNotFoundOrNullValue) {
+			t.Errorf("IP of %s error = %v, want ErrNotFoundOrNullValue", key, e)
+		}
+
+		if _, e := c.Get(key).CIDR(); !errors.Is(e, ErrNotFoundOrNullValue) {
+			t.Errorf("CIDR of %s error = %v, want ErrNotFoundOrNullValue", key, e)
+		}
+	}
+}
+
+func TestURL(t *testing.T) {
+	c := load(t, `{"url": "https://example.com:8443/path", "relative": "/path", "bad": "http://[::1", "null": null}`)
+
+	if u, e := c.Get("url").URL(); e != nil || u.Host != "example.com:8443" || u.Scheme != "https" {
+		t.Errorf("URL = %v, %v", u, e)
+	}
+
+	for _, key := range []string{"relative", "bad"} {
+		if _, e := c.Get(key).URL(); e == nil {
+			t.Errorf("URL of %s succeeded, want an error", key)
+		}
+	}
+
+	for _, key := range []string{"null", "absent"} {
+		if _, e := c.Get(key).URL(); !errors.Is(e, ErrNotFoundOrNullValue) {
+			t.Errorf("URL of %s error = %v, want ErrNotFoundOrNullValue", key, e)
+		}
+	}
+}
+
+func TestLoadJSONReader(t *testing.T) {
+	c, e := New().LoadJSONReader(strings.NewReader(`{"a": 1, "b": {"c": "x"}}`))
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	f, e := os.Open(writeFile(t, tempDir(t), "config.json", `{"a": 2}`))
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer f.Close()
+
+	if _, e := c.LoadJSONReader(f); e != nil {
+		t.Fatal(e)
+	}
+
+	if n, _ := c.Get("a").Int(); n != 2 {
+		t.Errorf("a = %d, want 2 loaded later from the file", n)
+	}
+
+	if s, _ := c.Get("b.c").String(); s != "x" {
+		t.Errorf("b.c = %q, want x", s)
+	}
+
+	if _, e := New().LoadJSONReader(strings.NewReader(`{"a": }`)); e == nil || !strings.Contains(e.Error(), "1:7") {
+		t.Errorf("LoadJSONReader of malformed data error = %v, want position 1:7", e)
+	}
+}
+
+func TestRequire(t *testing.T) {
+	c := load(t, `{"a": 1, "b": null}`)
+
+	if e := c.Require("a"); e != nil {
+		t.Error(e)
+	}
+
+	if e := c.Require("a", "b", "c"); e == nil || !strings.HasSuffix(e.Error(), "b, c") {
+		t.Errorf("Require error = %v, want b and c listed", e)
+	}
+}
+
+func TestToJSON(t *testing.T) {
+	setenv(t, "CT_JSON_USER", "env")
+	c := load(t, `{
+		"ct": {"json": {"user": "file", "servers": [{"port": 8080}, {"port": 8081}]}},
+		"id": 9007199254740993,
+		"ratio": 0.25,
+		"enabled": true,
+		"none": null
+	}`)
+
+	data, e := c.ToJSON()
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	var dumped map[string]interface{}
+	if e := json.Unmarshal(data, &dumped); e != nil {
+		t.Fatal(e)
+	}
+
+	if user := dumped["ct"].(map[string]interface{})["json"].(map[string]interface{})["user"]; user != "env" {
+		t.Errorf("dumped user = %v, want env", user)
+	}
+
+	loaded, e := New().LoadJSONBytes(data)
+	if e != nil {
+		t.Fatalf("loading the dump: %v", e)
+	}
+
+	again, e := loaded.ToJSON()
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	if string(again) != string(data) {
+		t.Errorf("dump after round trip = %s, want %s", again, data)
+	}
+
+	if n, e := loaded.Get("id").Int64(); e != nil || n != 9007199254740993 {
+		t.Errorf("id after round trip = %d, %v, want 9007199254740993", n, e)
+	}
+
+	if ratio, e := loaded.Get("ratio").Float64(); e != nil || ratio != 0.25 {
+		t.Errorf("ratio after round trip = %g, %v, want 0.25", ratio, e)
+	}
+}
+
+func TestToJSONRedacted(t *testing.T) {
+	c := load(t, `{"ct": {"json": {"servers": [{"password": "s3cret"}]}}, "password": "top"}`)
+
+	redacted, e := c.ToJSONRedacted("*.password", "password")
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	if strings.Contains(string(redacted), "s3cret") || strings.Contains(string(redacted), "top") {
+		t.Errorf("redacted dump contains secrets: %s", redacted)
+	}
+
+	if s, _ := c.Get("password").String(); s != "top" {
+		t.Errorf("password after redaction = %q, want top", s)
+	}
+
+	if _, e := c.ToJSONRedacted("["); e == nil {
+		t.Error("ToJSONRedacted with malformed pattern succeeded, want an error")
+	}
+}
+
+func TestLoadSecretsDir(t *testing.T) {
+	dir := tempDir(t)
+	writeFile(t, dir, "db.password", "  s3cret\n")
+	writeFile(t, dir, ".hidden", "hidden")
+	if e := os.Mkdir(filepath.Join(dir, "nested"), 0700); e != nil {
+		t.Fatal(e)
+	}
+
+	c, e := load(t, `{"db": {"password": "json", "user": "u"}}`).LoadSecretsDir(dir)
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	if s, _ := c.Get("db.password").String(); s != "s3cret" {
+		t.Errorf("db.password = %q, want s3cret", s)
+	}
+
+	if s, _ := c.Get("db.user").String(); s != "u" {
+		t.Errorf("db.user = %q, want u", s)
+	}
+
+	if c.Get(".hidden").Exists() || c.Get("nested").Exists() {
+		t.Error("hidden file or directory is loaded")
+	}
+
+	if _, e := New().LoadSecretsDir(filepath.Join(dir, "absent")); e == nil {
+		t.Error("LoadSecretsDir of absent directory succeeded, want an error")
+	}
+}
+
+func TestCaseInsensitive(t *testing.T) {
+	c := load(t, `{"db": {"user": "a"}, "Mixed": 1}`)
+
+	if c.Get("DB.User").Exists() {
+		t.Error("case sensitive lookup found DB.User")
+	}
+
+	c.CaseInsensitive()
+	if s, _ := c.Get("DB.User").String(); s != "a" {
+		t.Errorf("DB.User = %q, want a", s)
+	}
+
+	c.Set("DB.User", "b")
+	for _, key := range []string{"db.user", "DB.User"} {
+		if s, _ := c.Get(key).String(); s != "b" {
+			t.Errorf("Get(%q) after Set = %q, want b", key, s)
+		}
+	}
+
+	if m, _ := c.Get("db").MapOfString(); len(m) != 1 {
+		t.Errorf("db = %v, want a single key", m)
+	}
+}
+
+func TestClone(t *testing.T) {
+	c := load(t, `{"db": {"user": "a", "hosts": ["x"]}}`)
+
+	d := c.Clone()
+	d.Set("db.user", "b")
+	if s, _ := c.Get("db.user").String(); s != "a" {
+		t.Errorf("original after Set on clone = %q, want a", s)
+	}
+
+	if s, _ := d.Get("db.user").String(); s != "b" {
+		t.Errorf("clone after Set = %q, want b", s)
+	}
+}
+
+func TestSource(t *testing.T) {
+	setenv(t, "CT_SOURCE_ENV", "env")
+	c := load(t, `{"ct": {"source": {"file": 1}}}`)
+	c.Set("ct.source.set", 1)
+
+	tests := map[string]string{
+		"ct.source.env":    SourceEnv,
+		"ct.source.file":   SourceFile,
+		"ct.source.set":    SourceFile,
+		"ct.source.absent": SourceDefault,
+	}
+
+	for key, want := range tests {
+		if source := c.Get(key).Source(); source != want {
+			t.Errorf("Get(%q).Source() = %q, want %q", key, source, want)
+		}
+	}
+}
+
+func TestNumericStrings(t *testing.T) {
+	c := load(t, `{"v": 0}`)
+
+	tests := []struct {
+		value string
+		get   func(*Config) (interface{}, error)
+		want  interface{}
+		fails bool
+	}{
+		{"42", func(c *Config) (interface{}, error) { return c.Int() }, 42, false},
+		{" 42 ", func(c *Config) (interface{}, error) { return c.Int() }, 42, false},
+		{"+42", func(c *Config) (interface{}, error) { return c.Int() }, 42, false},
+		{"-42", func(c *Config) (interface{}, error) { return c.Int64() }, int64(-42), false},
+		{"010", func(c *Config) (interface{}, error) { return c.Int() }, 10, false},
+		{"0x10", func(c *Config) (interface{}, error) { return c.Int() }, 16, false},
+		{"0o10", func(c *Config) (interface{}, error) { return c.Uint() }, uint(8), false},
+		{"0b101", func(c *Config) (interface{}, error) { return c.Uint64() }, uint64(5), false},
+		{"1_000", func(c *Config) (interface{}, error) { return c.Int() }, 1000, false},
+		{"4.2", func(c *Config) (interface{}, error) { return c.Int() }, nil, true},
+		{"x", func(c *Config) (interface{}, error) { return c.Int() }, nil, true},
+		{"99999999999999999999", func(c *Config) (interface{}, error) { return c.Int64() }, nil, true},
+		{"+7", func(c *Config) (interface{}, error) { return c.Uint8() }, uint8(7), false},
+		{"-7", func(c *Config) (interface{}, error) { return c.Uint8() }, nil, true},
+		{"18446744073709551615", func(c *Config) (interface{}, error) { return c.Uint64() }, uint64(math.MaxUint64), false},
+		{" 2.5 ", func(c *Config) (interface{}, error) { return c.Float64() }, 2.5, false},
+		{"1e400", func(c *Config) (interface{}, error) { return c.Float64() }, nil, true},
+		{"x", func(c *Config) (interface{}, error) { return c.Float32() }, nil, true},
+	}
+
+	for _, test := range tests {
+		c.Set("v", test.value)
+		v, e := test.get(c.Get("v"))
+		if test.fails {
+			if e == nil {
+				t.Errorf("%q: got %v, want an error", test.value, v)
+			}
+		} else if e != nil || v != test.want {
+			t.Errorf("%q: got %v (%T), %v, want %v (%T)", test.value, v, v, e, test.want, test.want)
+		}
+	}
+}
+
+func TestNumericEnvPrecedence(t *testing.T) {
+	setenv(t, "CT_NUMERIC_PORT", "09090")
+	c := load(t, `{"ct": {"numeric": {"port": 8080}}}`)
+
+	if n, e := c.Get("ct.numeric.port").Int(); e != nil || n != 9090 {
+		t.Errorf("Int = %d, %v, want the environment variable 9090", n, e)
+	}
+
+	if n := c.Get("ct.numeric.port").Uint16OrElse(1); n != 9090 {
+		t.Errorf("Uint16OrElse = %d, want 9090", n)
+	}
+}
+
+func TestMapOfStringFromEnv(t *testing.T) {
+	tests := []struct {
+		value string
+		want  map[string]string
+		fails bool
+	}{
+		{"a=1,b=2", map[string]string{"a": "1", "b": "2"}, false},
+		{" a = 1 , b=x=y ", map[string]string{"a": "1", "b": "x=y"}, false},
+		{"a=", map[string]string{"a": ""}, false},
+		{"", map[string]string{}, false},
+		{"a=1,b", nil, true},
+		{"=1", nil, true},
+		{"a=1,,b=2", nil, true},
+	}
+
+	for _, test := range tests {
+		setenv(t, "CT_TAGS", test.value)
+		m, e := New().Get("ct.tags").MapOfStringFromEnv()
+		if test.fails {
+			if e == nil {
+				t.Errorf("%q: got %v, want an error", test.value, m)
+			}
+		} else if e != nil || !reflect.DeepEqual(m, test.want) {
+			t.Errorf("%q: got %v, %v, want %v", test.value, m, e, test.want)
+		}
+	}
+
+	if m, e := load(t, `{"tags": {"a": "1"}}`).Get("tags").MapOfStringFromEnv(); e != nil || m["a"] != "1" {
+		t.Errorf("object node = %v, %v", m, e)
+	}
+}
+
+func TestStringFirst(t *testing.T) {
+	c := load(t, `{"legacy": "", "current": "v", "number": 1}`)
+
+	if s, e := c.StringFirst("absent", "legacy", "current"); e != nil || s != "v" {
+		t.Errorf("StringFirst = %q, %v, want v", s, e)
+	}
+
+	if _, e := c.StringFirst("legacy", "absent"); !errors.Is(e, ErrNotFoundOrNullValue) {
+		t.Errorf("StringFirst of empty and absent keys error = %v, want ErrNotFoundOrNullValue", e)
+	}
+
+	if _, e := c.StringFirst("number", "current"); e == nil {
+		t.Error("StringFirst of non-string node succeeded, want an error")
+	}
+}
+
+func TestGetPointer(t *testing.T) {
+	c := load(t, `{"servers": [{"host": "a"}, {"host": "b", "a/b": {"~x": 1}}], "": "empty"}`)
+
+	tests := map[string]string{
+		"/servers/0/host": "a",
+		"/servers/1/host": "b",
+		"/":               "empty",
+	}
+
+	for ptr, want := range tests {
+		if s, e := c.GetPointer(ptr).String(); e != nil || s != want {
+			t.Errorf("GetPointer(%q) = %q, %v, want %q", ptr, s, e, want)
+		}
+	}
+
+	if n, _ := c.GetPointer("/servers/1/a~1b/~0x").Int(); n != 1 {
+		t.Errorf("escaped pointer = %d, want 1", n)
+	}
+
+	if s, _ := c.GetPointer("/servers/0").Get("host").String(); s != "a" {
+		t.Errorf("Get of pointed object = %q, want a", s)
+	}
+
+	if s, _ := c.Get("servers").GetPointer("/1/host").String(); s != "b" {
+		t.Errorf("relative pointer = %q, want b", s)
+	}
+
+	for _, ptr := range []string{"servers/0", "/servers/2/host", "/servers/-1", "/servers/01", "/servers/+1", "/servers/-", "/servers/x", "/servers/0/host/x"} {
+		if c.GetPointer(ptr).Exists() {
+			t.Errorf("GetPointer(%q) exists, want an empty instance", ptr)
+		}
+	}
+
+	if !c.GetPointer("").Exists() {
+		t.Error("GetPointer of whole document does not exist")
+	}
+}
+
+func TestSlice(t *testing.T) {
+	c := load(t, `{"servers": [{"host": "a", "port": 1}, {"host": "b", "port": 2}], "scalar": 1}`)
+
+	servers, e := c.Get("servers").Slice()
+	if e != nil || len(servers) != 2 {
+		t.Fatalf("Slice = %v, %v", servers, e)
+	}
+
+	for i, want := range []string{"a", "b"} {
+		if s, _ := servers[i].Get("host").String(); s != want {
+			t.Errorf("servers[%d].host = %q, want %q", i, s, want)
+		}
+	}
+
+	if _, e := servers[0].Get("absent").String(); e == nil || !strings.Contains(e.Error(), "servers.0.absent") {
+		t.Errorf("error = %v, want the key of element", e)
+	}
+
+	if _, e := c.Get("scalar").Slice(); e == nil {
+		t.Error("Slice of non-array node succeeded, want an error")
+	}
+
+	if _, e := c.Get("absent").Slice(); !errors.Is(e, ErrNotFoundOrNullValue) {
+		t.Errorf("Slice of absent key error = %v, want ErrNotFoundOrNullValue", e)
+	}
+}